@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/joyrex2001/nightshift/internal"
+)
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+}
+
+var explainCmd = &cobra.Command{
+	Use:   "explain <uid|namespace/name>...",
+	Short: "Explain how the schedule of the given objects is determined",
+	Args:  cobra.MinimumNArgs(1),
+	Run:   internal.Explain,
+}
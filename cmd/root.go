@@ -37,8 +37,18 @@ func init() {
 	rootCmd.PersistentFlags().String("cert-file", "", "TLS certificate file")
 	rootCmd.PersistentFlags().String("timezone", "Local", "Timezone in which schedules are defined")
 	rootCmd.PersistentFlags().Duration("interval", 15*time.Minute, "Agent resync period")
+	rootCmd.PersistentFlags().Int("scale-workers", 10, "Number of objects that are scaled concurrently")
+	rootCmd.PersistentFlags().Bool("watch-config", true, "Reload scanners and triggers when the config file changes")
+	rootCmd.PersistentFlags().Bool("strict-config", false, "Refuse to start if the config file is invalid")
+	rootCmd.PersistentFlags().Int("activity-size", 1000, "Number of activity records kept in memory")
+	rootCmd.PersistentFlags().String("activity-file", "", "File to persist the activity records to (jsonl)")
 	viper.BindPFlag("generic.timezone", rootCmd.PersistentFlags().Lookup("timezone"))
 	viper.BindPFlag("generic.interval", rootCmd.PersistentFlags().Lookup("interval"))
+	viper.BindPFlag("generic.scale-workers", rootCmd.PersistentFlags().Lookup("scale-workers"))
+	viper.BindPFlag("generic.watch-config", rootCmd.PersistentFlags().Lookup("watch-config"))
+	viper.BindPFlag("generic.strict-config", rootCmd.PersistentFlags().Lookup("strict-config"))
+	viper.BindPFlag("activity.size", rootCmd.PersistentFlags().Lookup("activity-size"))
+	viper.BindPFlag("activity.file", rootCmd.PersistentFlags().Lookup("activity-file"))
 	viper.BindPFlag("web.listen-addr", rootCmd.PersistentFlags().Lookup("listen-addr"))
 	viper.BindPFlag("web.enable", rootCmd.PersistentFlags().Lookup("enable-web"))
 	viper.BindPFlag("web.enable-tls", rootCmd.PersistentFlags().Lookup("enable-tls"))
@@ -58,6 +68,12 @@ func init() {
 		rootCmd.PersistentFlags().String("kubeconfig", "", "absolute path to the kubeconfig file")
 	}
 	viper.BindPFlag("openshift.kubeconfig", rootCmd.PersistentFlags().Lookup("kubeconfig"))
+	rootCmd.PersistentFlags().Bool("enable-events", true, "Record kubernetes events on scaled objects")
+	viper.BindPFlag("openshift.events", rootCmd.PersistentFlags().Lookup("enable-events"))
+	rootCmd.PersistentFlags().Bool("enable-schedule-crd", false, "Read schedules from NightshiftSchedule custom resources")
+	viper.BindPFlag("openshift.schedule-crd", rootCmd.PersistentFlags().Lookup("enable-schedule-crd"))
+	rootCmd.PersistentFlags().Duration("schedule-crd-interval", time.Minute, "Interval at which NightshiftSchedule custom resources are read")
+	viper.BindPFlag("openshift.schedule-crd-interval", rootCmd.PersistentFlags().Lookup("schedule-crd-interval"))
 }
 
 func homeDir() string {
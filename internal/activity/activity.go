@@ -0,0 +1,297 @@
+package activity
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// OutcomeSuccess is used to filter on records without an error.
+	OutcomeSuccess string = "success"
+	// OutcomeFailure is used to filter on records with an error.
+	OutcomeFailure string = "failure"
+)
+
+// Record describes a single action that has been taken by nightshift; e.g.
+// scaling an object, saving its state or executing a trigger.
+type Record struct {
+	Time        time.Time     `json:"time"`
+	Action      string        `json:"action"`
+	Namespace   string        `json:"namespace,omitempty"`
+	Object      string        `json:"object,omitempty"`
+	UID         string        `json:"uid,omitempty"`
+	ScannerId   string        `json:"scanner_id,omitempty"`
+	Schedule    string        `json:"schedule,omitempty"`
+	OldReplicas int           `json:"old_replicas"`
+	NewReplicas int           `json:"new_replicas"`
+	State       string        `json:"state,omitempty"`
+	TriggerId   string        `json:"trigger_id,omitempty"`
+	Status      int           `json:"status,omitempty"`
+	Attempts    int           `json:"attempts,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// Filter contains the criteria to select records. Empty fields match any
+// record.
+type Filter struct {
+	Namespace string
+	Object    string
+	Trigger   string
+	Outcome   string
+}
+
+type journal struct {
+	m           sync.Mutex
+	records     []Record
+	next        int
+	full        bool
+	file        *os.File
+	path        string
+	lines       int
+	subscribers []func(Record)
+}
+
+var instance = newJournal(1000)
+
+// newJournal will instantiate a new journal that keeps at most size records
+// in memory.
+func newJournal(size int) *journal {
+	if size < 1 {
+		size = 1
+	}
+	return &journal{records: make([]Record, size)}
+}
+
+// SetSize will set the number of records that are kept in memory. If the
+// journal shrinks, the oldest records will be discarded.
+func SetSize(size int) {
+	instance.resize(size)
+}
+
+// SetFile will persist the journal to the given file, with one json record
+// per line. Records that are already available in the file will be loaded
+// into the journal.
+func SetFile(path string) error {
+	return instance.setFile(path)
+}
+
+// Add will add a record to the journal. If the time of the record is not set,
+// it will be set to the current time.
+func Add(rec Record) {
+	instance.add(rec)
+}
+
+// Get will return the records that match the given filter, newest first.
+func Get(f Filter) []Record {
+	return instance.get(f)
+}
+
+// Subscribe will register the given function, which will be called with
+// every record that is added to the journal. The function should not block.
+func Subscribe(fn func(Record)) {
+	instance.subscribe(fn)
+}
+
+// subscribe will register the given function to be called with every record
+// that is added to the journal.
+func (j *journal) subscribe(fn func(Record)) {
+	j.m.Lock()
+	defer j.m.Unlock()
+	j.subscribers = append(j.subscribers, fn)
+}
+
+// resize will change the size of the ring buffer, keeping the newest
+// records.
+func (j *journal) resize(size int) {
+	if size < 1 {
+		size = 1
+	}
+	recs := j.get(Filter{})
+	j.m.Lock()
+	defer j.m.Unlock()
+	j.records = make([]Record, size)
+	j.next = 0
+	j.full = false
+	for i := len(recs) - 1; i >= 0; i-- {
+		j.push(recs[i])
+	}
+}
+
+// setFile will load the records in the given file, and will append new
+// records to it. The file is rewritten with just the records that are kept
+// in memory, so it does not grow with records that are no longer retained.
+func (j *journal) setFile(path string) error {
+	j.m.Lock()
+	defer j.m.Unlock()
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+	if fh, err := os.Open(path); err == nil {
+		scn := bufio.NewScanner(fh)
+		for scn.Scan() {
+			rec := Record{}
+			if err := json.Unmarshal(scn.Bytes(), &rec); err != nil {
+				glog.Errorf("Error reading activity record: %s", err)
+				continue
+			}
+			j.push(rec)
+		}
+		fh.Close()
+	}
+	j.path = path
+	return j.compact()
+}
+
+// compact will rewrite the journal file with the records that are kept in
+// memory, and reopen it for appending new records. It should be called while
+// holding the lock.
+func (j *journal) compact() error {
+	if j.file != nil {
+		j.file.Close()
+		j.file = nil
+	}
+	tmp := j.path + ".tmp"
+	fh, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	recs := j.list()
+	w := bufio.NewWriter(fh)
+	for i := len(recs) - 1; i >= 0; i-- {
+		line, err := json.Marshal(recs[i])
+		if err != nil {
+			continue
+		}
+		w.Write(append(line, '\n'))
+	}
+	err = w.Flush()
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, j.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	fh, err = os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	j.file = fh
+	j.lines = len(recs)
+	return nil
+}
+
+// add will add a record to the journal, write it to the journal file, if
+// configured, and pass it to the subscribers.
+func (j *journal) add(rec Record) {
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	j.m.Lock()
+	j.push(rec)
+	j.write(rec)
+	subs := j.subscribers
+	j.m.Unlock()
+	for _, fn := range subs {
+		fn(rec)
+	}
+}
+
+// write will write the record to the journal file, if configured. Once the
+// file contains twice the number of records that are kept in memory, it is
+// compacted. It should be called while holding the lock.
+func (j *journal) write(rec Record) {
+	if j.file == nil {
+		return
+	}
+	line, err := json.Marshal(rec)
+	if err == nil {
+		_, err = j.file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		glog.Errorf("Error writing activity record: %s", err)
+		return
+	}
+	j.lines++
+	if j.lines >= 2*len(j.records) {
+		if err := j.compact(); err != nil {
+			glog.Errorf("Error compacting activity file: %s", err)
+		}
+	}
+}
+
+// push will add a record to the ring buffer, overwriting the oldest record if
+// the buffer is full.
+func (j *journal) push(rec Record) {
+	j.records[j.next] = rec
+	j.next = (j.next + 1) % len(j.records)
+	if j.next == 0 {
+		j.full = true
+	}
+}
+
+// get will return the records that match the given filter, newest first.
+func (j *journal) get(f Filter) []Record {
+	j.m.Lock()
+	defer j.m.Unlock()
+	res := []Record{}
+	for _, rec := range j.list() {
+		if f.match(rec) {
+			res = append(res, rec)
+		}
+	}
+	return res
+}
+
+// list will return all records in the ring buffer, newest first. It should
+// be called while holding the lock.
+func (j *journal) list() []Record {
+	n := j.next
+	if j.full {
+		n = len(j.records)
+	}
+	res := make([]Record, 0, n)
+	for i := 1; i <= n; i++ {
+		res = append(res, j.records[(j.next-i+len(j.records))%len(j.records)])
+	}
+	return res
+}
+
+// match will return true if the given record matches the filter.
+func (f Filter) match(rec Record) bool {
+	if f.Namespace != "" && f.Namespace != rec.Namespace {
+		return false
+	}
+	if f.Object != "" && f.Object != rec.Object && f.Object != rec.UID {
+		return false
+	}
+	if f.Trigger != "" && f.Trigger != rec.TriggerId {
+		return false
+	}
+	if f.Outcome == OutcomeSuccess && rec.Error != "" {
+		return false
+	}
+	if f.Outcome == OutcomeFailure && rec.Error == "" {
+		return false
+	}
+	return true
+}
+
+// ErrorString will return the error message of given error, or an empty
+// string if the error is nil.
+func ErrorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,176 @@
+package activity
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRingBuffer(t *testing.T) {
+	tests := []struct {
+		size int
+		add  int
+		out  []int
+	}{
+		{size: 3, add: 0, out: []int{}},
+		{size: 3, add: 2, out: []int{1, 0}},
+		{size: 3, add: 3, out: []int{2, 1, 0}},
+		{size: 3, add: 7, out: []int{6, 5, 4}},
+	}
+	for i, tst := range tests {
+		j := newJournal(tst.size)
+		for r := 0; r < tst.add; r++ {
+			j.add(Record{NewReplicas: r})
+		}
+		res := []int{}
+		for _, rec := range j.get(Filter{}) {
+			res = append(res, rec.NewReplicas)
+		}
+		if !reflect.DeepEqual(res, tst.out) {
+			t.Errorf("failed test %d - expected %v, got %v", i, tst.out, res)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	j := newJournal(5)
+	for r := 0; r < 5; r++ {
+		j.add(Record{NewReplicas: r})
+	}
+	j.resize(2)
+	res := []int{}
+	for _, rec := range j.get(Filter{}) {
+		res = append(res, rec.NewReplicas)
+	}
+	if !reflect.DeepEqual(res, []int{4, 3}) {
+		t.Errorf("failed test - expected newest records to be kept, got %v", res)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	recs := []Record{
+		{Action: "scale", Namespace: "dev", Object: "shell", UID: "abc"},
+		{Action: "scale", Namespace: "dev", Object: "db", UID: "def", Error: "failed"},
+		{Action: "trigger", TriggerId: "refreshdb"},
+	}
+	tests := []struct {
+		filter Filter
+		count  int
+	}{
+		{filter: Filter{}, count: 3},
+		{filter: Filter{Namespace: "dev"}, count: 2},
+		{filter: Filter{Object: "shell"}, count: 1},
+		{filter: Filter{Object: "def"}, count: 1},
+		{filter: Filter{Trigger: "refreshdb"}, count: 1},
+		{filter: Filter{Outcome: OutcomeSuccess}, count: 2},
+		{filter: Filter{Outcome: OutcomeFailure}, count: 1},
+		{filter: Filter{Namespace: "dev", Outcome: OutcomeFailure}, count: 1},
+	}
+	j := newJournal(10)
+	for _, rec := range recs {
+		j.add(rec)
+	}
+	for i, tst := range tests {
+		if res := j.get(tst.filter); len(res) != tst.count {
+			t.Errorf("failed test %d - expected %d records, got %d", i, tst.count, len(res))
+		}
+	}
+}
+
+func TestSetFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "activity")
+	if err != nil {
+		t.Fatalf("failed test - unable to create tempdir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "activity.jsonl")
+
+	j := newJournal(10)
+	if err := j.setFile(path); err != nil {
+		t.Fatalf("failed test - unexpected error: %s", err)
+	}
+	j.add(Record{Action: "scale", Time: time.Unix(10, 0), Duration: time.Second})
+	j.add(Record{Action: "trigger", Time: time.Unix(20, 0), Error: ErrorString(errors.New("failed"))})
+	j.file.Close()
+
+	j2 := newJournal(10)
+	if err := j2.setFile(path); err != nil {
+		t.Fatalf("failed test - unexpected error: %s", err)
+	}
+	defer j2.file.Close()
+	res := j2.get(Filter{})
+	if len(res) != 2 {
+		t.Fatalf("failed test - expected 2 records loaded from file, got %d", len(res))
+	}
+	if res[0].Action != "trigger" || res[0].Error != "failed" || res[1].Duration != time.Second {
+		t.Errorf("failed test - records not correctly restored, got %v", res)
+	}
+}
+
+func TestCompactFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "activity")
+	if err != nil {
+		t.Fatalf("failed test - unable to create tempdir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "activity.jsonl")
+	lines := func() int {
+		data, _ := ioutil.ReadFile(path)
+		return strings.Count(string(data), "\n")
+	}
+
+	j := newJournal(10)
+	if err := j.setFile(path); err != nil {
+		t.Fatalf("failed test - unexpected error: %s", err)
+	}
+	for i := 0; i < 5; i++ {
+		j.add(Record{Action: "scale", Time: time.Unix(int64(i), 0)})
+	}
+	j.file.Close()
+
+	// loading the file will truncate it to the retained records
+	j = newJournal(3)
+	if err := j.setFile(path); err != nil {
+		t.Fatalf("failed test - unexpected error: %s", err)
+	}
+	if n := lines(); n != 3 {
+		t.Errorf("failed test - expected 3 records in file after loading, got %d", n)
+	}
+
+	// the file is compacted once it contains twice the retained records
+	tests := []int{4, 5, 3, 4}
+	for i, exp := range tests {
+		j.add(Record{Action: "scale", Time: time.Unix(int64(10+i), 0)})
+		if n := lines(); n != exp {
+			t.Errorf("failed test %d - expected %d records in file, got %d", i, exp, n)
+		}
+	}
+	j.file.Close()
+
+	j2 := newJournal(3)
+	if err := j2.setFile(path); err != nil {
+		t.Fatalf("failed test - unexpected error: %s", err)
+	}
+	defer j2.file.Close()
+	if res := j2.get(Filter{}); len(res) != 3 || res[0].Time.Unix() != 13 || res[2].Time.Unix() != 11 {
+		t.Errorf("failed test - expected the newest records to be kept, got %v", res)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	j := newJournal(10)
+	got := []string{}
+	j.subscribe(func(rec Record) {
+		got = append(got, rec.Action)
+	})
+	j.add(Record{Action: "scale"})
+	j.add(Record{Action: "save"})
+	if len(got) != 2 || got[0] != "scale" || got[1] != "save" {
+		t.Errorf("failed test - expected subscriber to receive scale and save, got %v", got)
+	}
+}
@@ -15,25 +15,36 @@ type Agent interface {
 	AddScanner(scanner.Scanner)
 	AddTrigger(string, trigger.Trigger)
 	SetResyncInterval(time.Duration)
+	SetScaleWorkers(int)
 	GetObjects() map[string]*scanner.Object
+	Explain(string) (*Explanation, error)
 	GetScanners() []scanner.Scanner
 	GetTriggers() map[string]trigger.Trigger
+	GetDeadLetters() []DeadLetter
+	RetryTrigger(string) (int, error)
+	Wake([]trigger.EventObject)
+	Reload([]scanner.Scanner, map[string]trigger.Trigger)
 	UpdateSchedule()
 	Start()
 	Stop()
 }
 
 type worker struct {
-	interval  time.Duration
-	m         sync.Mutex
-	done      chan bool
-	scanners  []scanner.Scanner
-	triggers  map[string]trigger.Trigger
-	trigqueue chan string
-	watchers  []watch
-	objects   map[string]*objectspq
-	now       time.Time
-	past      time.Time
+	interval    time.Duration
+	workers     int
+	m           sync.Mutex
+	done        chan bool
+	scanners    []scanner.Scanner
+	triggers    map[string]trigger.Trigger
+	runner      *runner
+	deadletters []DeadLetter
+	watchers    []watch
+	objects     map[string]*objectspq
+	timeline    timeline
+	entries     map[string]*entry
+	wake        chan bool
+	past        time.Time
+	running     bool
 }
 
 var instance *worker
@@ -43,14 +54,17 @@ var once sync.Once
 func New() Agent {
 	once.Do(func() {
 		instance = &worker{
-			objects:   map[string]*objectspq{},
-			interval:  15 * time.Minute,
-			watchers:  []watch{},
-			done:      make(chan bool),
-			past:      time.Now().Add(-60 * time.Minute),
-			scanners:  []scanner.Scanner{},
-			triggers:  map[string]trigger.Trigger{},
-			trigqueue: make(chan string, 500),
+			objects:  map[string]*objectspq{},
+			interval: 15 * time.Minute,
+			workers:  10,
+			watchers: []watch{},
+			done:     make(chan bool),
+			wake:     make(chan bool, 1),
+			entries:  map[string]*entry{},
+			past:     time.Now().Add(-60 * time.Minute),
+			scanners: []scanner.Scanner{},
+			triggers: map[string]trigger.Trigger{},
+			runner:   newRunner(),
 		}
 	})
 	return instance
@@ -62,6 +76,15 @@ func (a *worker) SetResyncInterval(interval time.Duration) {
 	a.interval = interval
 }
 
+// SetScaleWorkers will set the number of objects that are scaled
+// concurrently.
+func (a *worker) SetScaleWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	a.workers = n
+}
+
 // AddScanner will add a scanner to the agent.
 func (a *worker) AddScanner(scnr scanner.Scanner) {
 	a.m.Lock()
@@ -71,9 +94,8 @@ func (a *worker) AddScanner(scnr scanner.Scanner) {
 
 // GetScanners will return the configured scanners.
 func (a *worker) GetScanners() []scanner.Scanner {
-	// disabled; AddScanner is only done during initialization...
-	// a.m.Lock()
-	// defer a.m.Unlock()
+	a.m.Lock()
+	defer a.m.Unlock()
 	return a.scanners
 }
 
@@ -82,27 +104,42 @@ func (a *worker) AddTrigger(id string, trgr trigger.Trigger) {
 	a.m.Lock()
 	defer a.m.Unlock()
 	a.triggers[id] = trgr
+	a.reschedule(triggerKey(id))
 }
 
-// GetScanners will return the configured scanners.
+// GetTriggers will return the configured triggers.
 func (a *worker) GetTriggers() map[string]trigger.Trigger {
-	// disabled; AddTrigger is only done during initialization...
-	// a.m.Lock()
-	// defer a.m.Unlock()
+	a.m.Lock()
+	defer a.m.Unlock()
 	return a.triggers
 }
 
+// getTrigger will return the trigger with given id, or false if no such
+// trigger is configured.
+func (a *worker) getTrigger(id string) (trigger.Trigger, bool) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	trgr, ok := a.triggers[id]
+	return trgr, ok
+}
+
 // Start will start the agent.
 func (a *worker) Start() {
 	glog.Info("Starting agent...")
+	a.m.Lock()
+	a.running = true
+	a.m.Unlock()
 	a.UpdateSchedule()
-	go a.StartWatch()
+	go a.watch(a.initWatchers())
 	go a.StartScale()
 	go a.StartTrigger()
 }
 
 // Stop will stop the agent.
 func (a *worker) Stop() {
+	a.m.Lock()
+	a.running = false
+	a.m.Unlock()
 	a.StopWatch()
 	a.StopScale()
 	a.StopTrigger()
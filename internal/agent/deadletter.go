@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+// maxDeadLetters is the maximum number of failed trigger executions that are
+// kept; if more executions fail, the oldest ones are dropped.
+const maxDeadLetters = 100
+
+// DeadLetter is a trigger execution that failed after all retries. It can be
+// inspected, and re-run with RetryTrigger.
+type DeadLetter struct {
+	TriggerId string        `json:"trigger_id"`
+	Event     trigger.Event `json:"event"`
+	Time      time.Time     `json:"time"`
+	Attempts  int           `json:"attempts"`
+	Error     string        `json:"error"`
+}
+
+// addDeadLetter will add a failed trigger execution to the dead-letter list.
+func (a *worker) addDeadLetter(dl DeadLetter) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.deadletters = append(a.deadletters, dl)
+	if n := len(a.deadletters); n > maxDeadLetters {
+		a.deadletters = a.deadletters[n-maxDeadLetters:]
+	}
+}
+
+// GetDeadLetters will return the trigger executions that failed.
+func (a *worker) GetDeadLetters() []DeadLetter {
+	a.m.Lock()
+	defer a.m.Unlock()
+	res := make([]DeadLetter, len(a.deadletters))
+	copy(res, a.deadletters)
+	return res
+}
+
+// RetryTrigger will remove the failed executions of the trigger with given id
+// from the dead-letter list, and queue them for execution again. It will
+// return the number of queued executions, or an error if the trigger does not
+// exist, or has no failed executions.
+func (a *worker) RetryTrigger(id string) (int, error) {
+	if _, ok := a.getTrigger(id); !ok {
+		return 0, fmt.Errorf("trigger not found: %s", id)
+	}
+	a.m.Lock()
+	retry := []DeadLetter{}
+	keep := []DeadLetter{}
+	for _, dl := range a.deadletters {
+		if dl.TriggerId == id {
+			retry = append(retry, dl)
+		} else {
+			keep = append(keep, dl)
+		}
+	}
+	a.deadletters = keep
+	a.m.Unlock()
+	if len(retry) == 0 {
+		return 0, fmt.Errorf("no failed executions for trigger: %s", id)
+	}
+	for _, dl := range retry {
+		a.queueTrigger(dl.TriggerId, dl.Event)
+	}
+	return len(retry), nil
+}
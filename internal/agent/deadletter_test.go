@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+func TestAddDeadLetter(t *testing.T) {
+	agent := &worker{}
+	for i := 0; i < maxDeadLetters+5; i++ {
+		agent.addDeadLetter(DeadLetter{TriggerId: "trigger1", Attempts: i})
+	}
+	res := agent.GetDeadLetters()
+	if len(res) != maxDeadLetters {
+		t.Errorf("failed test - expected %d dead letters, got %d", maxDeadLetters, len(res))
+	}
+	if res[0].Attempts != 5 {
+		t.Errorf("failed test - expected oldest dead letters to be dropped, got %d", res[0].Attempts)
+	}
+}
+
+func TestExecuteTriggerDeadLetter(t *testing.T) {
+	agent := &worker{}
+	mock := &mockTrigger{err: errors.New("failed")}
+	agent.executeTrigger("trigger1", mock, trigger.Event{ScannerId: "scanner1"})
+	res := agent.GetDeadLetters()
+	if len(res) != 1 {
+		t.Fatalf("failed test - expected 1 dead letter, got %d", len(res))
+	}
+	if res[0].TriggerId != "trigger1" || res[0].Event.ScannerId != "scanner1" || res[0].Error != "failed" || res[0].Attempts != 1 {
+		t.Errorf("failed test - unexpected dead letter %#v", res[0])
+	}
+	agent.executeTrigger("trigger1", &mockTrigger{}, trigger.Event{})
+	if len(agent.GetDeadLetters()) != 1 {
+		t.Errorf("failed test - successful execution added to dead letters")
+	}
+}
+
+func TestRetryTrigger(t *testing.T) {
+	tests := []struct {
+		id     string
+		queued int
+		left   int
+		err    bool
+	}{
+		{id: "unknown", queued: 0, left: 3, err: true},
+		{id: "trigger3", queued: 0, left: 3, err: true},
+		{id: "trigger1", queued: 2, left: 1, err: false},
+	}
+	for i, tst := range tests {
+		agent := &worker{}
+		agent.runner = newRunner()
+		agent.triggers = map[string]trigger.Trigger{
+			"trigger1": &mockTrigger{},
+			"trigger2": &mockTrigger{},
+			"trigger3": &mockTrigger{},
+		}
+		agent.addDeadLetter(DeadLetter{TriggerId: "trigger1", Event: trigger.Event{Time: time.Unix(1, 0)}})
+		agent.addDeadLetter(DeadLetter{TriggerId: "trigger2", Event: trigger.Event{Time: time.Unix(2, 0)}})
+		agent.addDeadLetter(DeadLetter{TriggerId: "trigger1", Event: trigger.Event{Time: time.Unix(3, 0)}})
+		n, err := agent.RetryTrigger(tst.id)
+		queued := 0
+		if q, ok := agent.runner.queues[tst.id]; ok {
+			queued = len(q.pending)
+		}
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error: %v", i, err)
+		}
+		if n != tst.queued || queued != tst.queued {
+			t.Errorf("failed test %d - expected %d queued, got %d (%d)", i, tst.queued, n, queued)
+		}
+		if l := len(agent.GetDeadLetters()); l != tst.left {
+			t.Errorf("failed test %d - expected %d dead letters left, got %d", i, tst.left, l)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+// Explanation describes how the effective schedule of an object has been
+// determined.
+type Explanation struct {
+	UID       string   `json:"uid"`
+	Cluster   string   `json:"cluster"`
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Matches   []Match  `json:"matches"`
+	Override  string   `json:"override"`
+	Schedule  []string `json:"schedule"`
+}
+
+// Match describes a scanner that matched an object. The matches are ordered
+// by priority, and the first match is the one that is effective.
+type Match struct {
+	ScannerId string   `json:"scanner_id"`
+	Priority  int      `json:"priority"`
+	Selector  string   `json:"selector"`
+	Schedule  []string `json:"schedule"`
+	Effective bool     `json:"effective"`
+}
+
+// Explain will return an explanation of how the effective schedule for the
+// object with given uid has been determined. It will return an error if the
+// object is not known.
+func (a *worker) Explain(uid string) (*Explanation, error) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	opq, ok := a.objects[uid]
+	if !ok || len(*opq) == 0 {
+		return nil, fmt.Errorf("object not found: %s", uid)
+	}
+	objs := make([]*scanner.Object, len(*opq))
+	copy(objs, *opq)
+	sort.Slice(objs, func(i, j int) bool { return objs[i].Priority > objs[j].Priority })
+
+	eff := objs[0]
+	expl := &Explanation{
+		UID:       eff.UID,
+		Cluster:   eff.Cluster,
+		Namespace: eff.Namespace,
+		Name:      eff.Name,
+		Type:      eff.Type,
+		Matches:   []Match{},
+		Override:  eff.Override,
+		Schedule:  descriptions(eff.Schedule),
+	}
+	for i, obj := range objs {
+		m := Match{
+			ScannerId: obj.ScannerId,
+			Priority:  obj.Priority,
+			Effective: i == 0,
+		}
+		if cfg, err := obj.GetScannerConfig(); err == nil {
+			m.Selector = cfg.Label
+			m.Schedule = descriptions(cfg.Schedule)
+		}
+		expl.Matches = append(expl.Matches, m)
+	}
+	return expl, nil
+}
+
+// descriptions will return the descriptions of the given schedules.
+func descriptions(scheds []*schedule.Schedule) []string {
+	res := []string{}
+	for _, s := range scheds {
+		res = append(res, s.Description)
+	}
+	return res
+}
@@ -0,0 +1,44 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+func TestExplain(t *testing.T) {
+	sched, _ := schedule.New("Mon-Fri 18:00 replicas=0")
+	wrkr := &worker{}
+	wrkr.InitObjects()
+	wrkr.addObject(&scanner.Object{UID: "abc", Name: "shell", Priority: 1, ScannerId: "default", Type: "myscanner"})
+	wrkr.addObject(&scanner.Object{UID: "abc", Name: "shell", Priority: 3, ScannerId: "shell", Type: "myscanner",
+		Override: scanner.OverrideSchedule, Schedule: []*schedule.Schedule{sched}})
+	wrkr.addObject(&scanner.Object{UID: "abc", Name: "shell", Priority: 2, ScannerId: "other", Type: "myscanner"})
+
+	if _, err := wrkr.Explain("def"); err == nil {
+		t.Errorf("failed test - expected error for unknown object, but got none")
+	}
+
+	expl, err := wrkr.Explain("abc")
+	if err != nil {
+		t.Fatalf("failed test - unexpected error: %s", err)
+	}
+	if expl.Override != scanner.OverrideSchedule {
+		t.Errorf("failed test - expected override %s, got %s", scanner.OverrideSchedule, expl.Override)
+	}
+	if !reflect.DeepEqual(expl.Schedule, []string{"mon-fri 18:00 replicas=0"}) {
+		t.Errorf("failed test - unexpected schedule %v", expl.Schedule)
+	}
+	ids := []string{}
+	for i, m := range expl.Matches {
+		ids = append(ids, m.ScannerId)
+		if m.Effective != (i == 0) {
+			t.Errorf("failed test - only the first match should be effective, got %v", expl.Matches)
+		}
+	}
+	if !reflect.DeepEqual(ids, []string{"shell", "other", "default"}) {
+		t.Errorf("failed test - matches not ordered by priority, got %v", ids)
+	}
+}
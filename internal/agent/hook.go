@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/joyrex2001/nightshift/internal/activity"
+	"github.com/joyrex2001/nightshift/internal/metrics"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+// vetoError is the error that is returned when a before hook fails, and
+// thereby vetoes the event.
+type vetoError struct {
+	hook string
+	err  error
+}
+
+// Error will return the reason of the veto, as required by the error
+// interface.
+func (e *vetoError) Error() string {
+	return fmt.Sprintf("vetoed by before hook %s: %s", e.hook, e.err)
+}
+
+// runBeforeHooks will execute the before hooks of given event synchronously,
+// in the configured order. It will return an error as soon as a hook fails,
+// in which case the event should not be handled.
+func (a *worker) runBeforeHooks(e *event) *vetoError {
+	for _, inv := range e.sched.GetBeforeHooks() {
+		if err := a.runHook(inv, newHookEvent(e, e.obj.Replicas, plannedReplicas(e))); err != nil {
+			return &vetoError{hook: inv.Id, err: err}
+		}
+	}
+	return nil
+}
+
+// vetoPolicy will return the veto policy of the trigger with given id. It will
+// return the default policy, which skips vetoed events, if the trigger does
+// not exist, or if its policy is invalid.
+func (a *worker) vetoPolicy(id string) *trigger.VetoPolicy {
+	def, _ := trigger.NewVetoPolicy(nil)
+	trgr, ok := a.getTrigger(id)
+	if !ok {
+		return def
+	}
+	policy, err := trigger.NewVetoPolicy(trgr.GetConfig().Settings)
+	if err != nil {
+		glog.Errorf("Error veto policy of trigger %s, using defaults: %s", id, err)
+		return def
+	}
+	return policy
+}
+
+// runAfterHooks will execute the after hooks of given event synchronously,
+// in the configured order. Failing hooks are logged, and do not prevent the
+// other hooks from being executed.
+func (a *worker) runAfterHooks(e *event, old int) {
+	for _, inv := range e.sched.GetAfterHooks() {
+		if err := a.runHook(inv, newHookEvent(e, old, e.obj.Replicas)); err != nil {
+			glog.Errorf("Error executing after hook %s: %s", inv.Id, err)
+		}
+	}
+}
+
+// runHook will execute the referred trigger synchronously, with the
+// parameters of the reference, retrying it as configured, and record the
+// result in the activity journal. It will return an error if the trigger
+// does not exist, or failed.
+func (a *worker) runHook(inv schedule.Invocation, evt trigger.Event) error {
+	id := inv.Id
+	trgr, ok := a.getTrigger(id)
+	if !ok {
+		return fmt.Errorf("trigger not found: %s", id)
+	}
+	evt.Params = inv.Params
+	start := time.Now()
+	attempts, err := trigger.ExecuteWithRetry(trgr, evt)
+	metrics.TriggerExecuted(id, err)
+	rec := newTriggerRecord(id, start, attempts, err)
+	rec.Action = "hook"
+	if len(evt.Objects) > 0 {
+		obj := evt.Objects[0]
+		rec.Namespace, rec.Object, rec.UID = obj.Namespace, obj.Name, obj.UID
+		rec.ScannerId, rec.Schedule = obj.ScannerId, obj.Schedule
+		rec.OldReplicas, rec.NewReplicas = obj.OldReplicas, obj.NewReplicas
+	}
+	activity.Add(rec)
+	return err
+}
+
+// newHookEvent will return the trigger event for a hook of given event, with
+// the given number of replicas before and after scaling.
+func newHookEvent(e *event, old, new int) trigger.Event {
+	return trigger.Event{
+		Time:      e.at,
+		ScannerId: e.obj.ScannerId,
+		Schedule:  e.sched.Description,
+		Objects: []trigger.EventObject{{
+			Namespace:   e.obj.Namespace,
+			Name:        e.obj.Name,
+			UID:         e.obj.UID,
+			ScannerId:   e.obj.ScannerId,
+			Schedule:    e.sched.Description,
+			OldReplicas: old,
+			NewReplicas: new,
+			NextScaleUp: nextScaleUp(e.obj.Schedule, e.at),
+		}},
+	}
+}
+
+// plannedReplicas will return the number of replicas the object of given
+// event will be scaled to when the event is handled.
+func plannedReplicas(e *event) int {
+	if state, _ := e.sched.GetState(); state == schedule.RestoreState && e.obj.State != nil {
+		return e.obj.State.Replicas
+	}
+	if repl, err := e.sched.GetReplicas(); err == nil {
+		return repl
+	}
+	return e.obj.Replicas
+}
@@ -0,0 +1,141 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+func TestHooks(t *testing.T) {
+	mock := &mockScanner{}
+	scanner.RegisterModule("hookscanner", getScannerFactory("hookscanner", mock))
+	past := time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC) // monday
+	now := time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		sched    string
+		before   error
+		scale    int
+		errors   int
+		triggers int
+		after    int
+	}{
+		{
+			sched:    "Mon 18:00 replicas=0 trigger=report",
+			scale:    0,
+			triggers: 1,
+		},
+		{
+			sched:    "Mon 18:00 replicas=0 before=backup after=notify trigger=report",
+			scale:    0,
+			triggers: 1,
+			after:    1,
+		},
+		{
+			sched:  "Mon 18:00 replicas=0 before=backup after=notify trigger=report",
+			before: errors.New("backup failed"),
+			scale:  -1,
+			errors: 1,
+		},
+		{
+			sched:  "Mon 18:00 replicas=0 before=unknown after=notify",
+			scale:  -1,
+			errors: 1,
+		},
+	}
+	for i, tst := range tests {
+		backup := &mockTrigger{err: tst.before}
+		notify := &mockTrigger{}
+		wrkr := &worker{triggers: map[string]trigger.Trigger{
+			"backup": backup,
+			"notify": notify,
+			"report": &mockTrigger{},
+		}}
+		mock.scale = -1
+		obj := newScheduledObject("obj", tst.sched)
+		obj.Type = "hookscanner"
+		obj.Replicas = 2
+
+		res := wrkr.scaleObject(due{obj: obj, past: past}, now)
+		if mock.scale != tst.scale {
+			t.Errorf("failed test %d - expected scale to %d, got %d", i, tst.scale, mock.scale)
+		}
+		if res.errors != tst.errors {
+			t.Errorf("failed test %d - expected %d errors, got %d", i, tst.errors, res.errors)
+		}
+		if len(res.triggers) != tst.triggers {
+			t.Errorf("failed test %d - expected %d triggers, got %d", i, tst.triggers, len(res.triggers))
+		}
+		if notify.exc != tst.after {
+			t.Errorf("failed test %d - expected %d after hook executions, got %d", i, tst.after, notify.exc)
+		}
+		if backup.exc > 0 {
+			objs := backup.evt.Objects
+			if len(objs) != 1 || objs[0].UID != "obj" || objs[0].OldReplicas != 2 || objs[0].NewReplicas != 0 {
+				t.Errorf("failed test %d - unexpected before hook event %v", i, backup.evt)
+			}
+		}
+	}
+}
+
+func TestHookVetoRetry(t *testing.T) {
+	mock := &mockScanner{}
+	scanner.RegisterModule("retryscanner", getScannerFactory("retryscanner", mock))
+	past := time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC) // monday
+	now := time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)
+	event := time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)
+
+	backup := &mockTrigger{
+		err: errors.New("backup failed"),
+		cfg: trigger.Config{Settings: map[string]string{"onveto": "retry", "vetoretries": "1", "vetodelay": "10m"}},
+	}
+	wrkr := &worker{past: past, triggers: map[string]trigger.Trigger{"backup": backup}}
+	wrkr.InitObjects()
+	obj := newScheduledObject("obj", "Mon 18:00 replicas=0 before=backup", "Mon 22:00 replicas=1")
+	obj.Type = "retryscanner"
+	obj.Replicas = 2
+	wrkr.addObject(obj)
+	mock.scale = -1
+
+	// the vetoed event is retried, and the events after it are postponed
+	dues := wrkr.popDue(now)
+	if len(dues) != 1 {
+		t.Fatalf("failed test - expected object to be due, got %v", dues)
+	}
+	res := wrkr.scaleObject(dues[0], now)
+	if mock.scale != -1 || res.events != 1 || res.errors != 1 {
+		t.Errorf("failed test - expected vetoed event to stop processing, got scale %d, %d events", mock.scale, res.events)
+	}
+	if res.retry == nil || !res.retry.event.Equal(event) || !res.retry.at.Equal(now.Add(10*time.Minute)) || res.retry.retries != 1 {
+		t.Fatalf("failed test - expected event to be retried in 10m, got %v", res.retry)
+	}
+	wrkr.retryEvent("obj", *res.retry)
+	if next, _ := wrkr.nextWakeup(); !next.Equal(res.retry.at) {
+		t.Errorf("failed test - expected retry to be next on timeline, got %s", next)
+	}
+	if dues := wrkr.popDue(now.Add(5 * time.Minute)); len(dues) != 0 {
+		t.Errorf("failed test - expected retry not to be due yet, got %v", dues)
+	}
+
+	// the retry exhausts the number of retries, and the event is skipped
+	now = res.retry.at
+	dues = wrkr.popDue(now)
+	if len(dues) != 1 || dues[0].retries != 1 || !dues[0].past.Equal(event) {
+		t.Fatalf("failed test - expected retry to be due since %s, got %v", event, dues)
+	}
+	res = wrkr.scaleObject(dues[0], now)
+	if res.retry != nil || res.events != 2 || mock.scale != 1 {
+		t.Errorf("failed test - expected event to be skipped after retries, got retry %v, scale %d", res.retry, mock.scale)
+	}
+
+	// a retry of which the before hook succeeds will scale the object
+	backup.err = nil
+	mock.scale = -1
+	res = wrkr.scaleObject(due{obj: obj, past: event, retries: 1}, now)
+	if res.retry != nil || res.errors != 0 || mock.scale != 1 {
+		t.Errorf("failed test - expected successful retry to scale, got %d errors, scale %d", res.errors, mock.scale)
+	}
+}
@@ -67,6 +67,8 @@ func (a *worker) InitObjects() {
 	a.m.Lock()
 	defer a.m.Unlock()
 	a.objects = map[string]*objectspq{}
+	a.timeline = timeline{}
+	a.entries = map[string]*entry{}
 }
 
 // GetObjects will go through all object priority queues, and for each object
@@ -91,11 +93,18 @@ func (a *worker) GetObjects() map[string]*scanner.Object {
 func (a *worker) addObject(obj *scanner.Object) {
 	a.m.Lock()
 	defer a.m.Unlock()
-	opq, ok := a.objects[obj.UID]
+	pushObject(a.objects, obj)
+	a.reschedule(obj.UID)
+}
+
+// pushObject will add (or replace) an object to the given collection of
+// object priority queues.
+func pushObject(objects map[string]*objectspq, obj *scanner.Object) {
+	opq, ok := objects[obj.UID]
 	if !ok {
 		// no entries yet, init the heap!
 		opq := &objectspq{obj}
-		a.objects[obj.UID] = opq
+		objects[obj.UID] = opq
 		heap.Init(opq)
 		return
 	}
@@ -120,4 +129,8 @@ func (a *worker) removeObject(obj *scanner.Object) {
 	if idx := opq.Index(obj); idx >= 0 {
 		heap.Remove(opq, idx)
 	}
+	if len(*opq) == 0 {
+		delete(a.objects, obj.UID)
+	}
+	a.reschedule(obj.UID)
 }
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+// Reload will atomically replace the configured scanners and triggers with
+// the given set. The currently known objects, as well as the time until which
+// their events have been processed, are kept, so no scheduled events will be
+// missed while reloading. If the agent is running, the watchers are restarted
+// for the new set of scanners; the new watchers are initialized before
+// returning, so a subsequent Reload will stop them.
+func (a *worker) Reload(scnrs []scanner.Scanner, trgrs map[string]trigger.Trigger) {
+	a.m.Lock()
+	logScannerDiff(a.scanners, scnrs)
+	logTriggerDiff(a.triggers, trgrs)
+	a.scanners = scnrs
+	a.triggers = trgrs
+	running := a.running
+	a.m.Unlock()
+
+	if running {
+		a.StopWatch()
+	}
+	a.UpdateSchedule()
+	if running {
+		go a.watch(a.initWatchers())
+	}
+}
+
+// logScannerDiff will log the scanners that have been added and removed when
+// replacing the old set of scanners with the new set.
+func logScannerDiff(old, new []scanner.Scanner) {
+	oldk := map[string]bool{}
+	for _, scnr := range old {
+		oldk[scannerKey(scnr.GetConfig())] = true
+	}
+	newk := map[string]bool{}
+	for _, scnr := range new {
+		newk[scannerKey(scnr.GetConfig())] = true
+	}
+	for k := range newk {
+		if !oldk[k] {
+			glog.Infof("Reload: added scanner %s", k)
+		}
+	}
+	for k := range oldk {
+		if !newk[k] {
+			glog.Infof("Reload: removed scanner %s", k)
+		}
+	}
+}
+
+// logTriggerDiff will log the triggers that have been added, removed or
+// changed when replacing the old set of triggers with the new set.
+func logTriggerDiff(old, new map[string]trigger.Trigger) {
+	for id, trgr := range new {
+		prev, ok := old[id]
+		if !ok {
+			glog.Infof("Reload: added trigger %s", id)
+			continue
+		}
+		if !reflect.DeepEqual(prev.GetConfig(), trgr.GetConfig()) {
+			glog.Infof("Reload: changed trigger %s", id)
+		}
+	}
+	for id := range old {
+		if _, ok := new[id]; !ok {
+			glog.Infof("Reload: removed trigger %s", id)
+		}
+	}
+}
+
+// scannerKey will return a textual representation of a scanner config which
+// is used to compare scanners while reloading.
+func scannerKey(cfg scanner.Config) string {
+	sched := []string{}
+	for _, s := range cfg.Schedule {
+		sched = append(sched, s.Description)
+	}
+	return fmt.Sprintf("type=%s cluster=%s namespace=%s label=%s id=%s priority=%d schedule=[%s]",
+		cfg.Type, cfg.Cluster, cfg.Namespace, cfg.Label, cfg.Id, cfg.Priority, strings.Join(sched, ";"))
+}
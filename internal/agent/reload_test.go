@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+func TestReload(t *testing.T) {
+	past := time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC)
+	wrkr := &worker{past: past, triggers: map[string]trigger.Trigger{}}
+	wrkr.InitObjects()
+	sched, _ := schedule.New("Mon-Fri 8:00 replicas=1")
+	wrkr.AddScanner(&mockScanner{objs: []*scanner.Object{{UID: "abc", Schedule: []*schedule.Schedule{sched}}}})
+	wrkr.AddTrigger("foo", &mockTrigger{id: "foo"})
+	wrkr.UpdateSchedule()
+
+	// abc has been processed until the last tick of the scale loop
+	tick := past.Add(time.Hour)
+	wrkr.entries["abc"].past = tick
+	wrkr.past = time.Time{}
+	scnr := &mockScanner{objs: []*scanner.Object{
+		{UID: "abc", Schedule: []*schedule.Schedule{sched}},
+		{UID: "def", Schedule: []*schedule.Schedule{sched}},
+	}}
+	wrkr.Reload([]scanner.Scanner{scnr}, map[string]trigger.Trigger{
+		"bar": &mockTrigger{id: "bar"},
+	})
+
+	if scnrs := wrkr.GetScanners(); len(scnrs) != 1 || scnrs[0] != scnr {
+		t.Errorf("failed Reload - scanners not replaced, got %v", scnrs)
+	}
+	trgrs := wrkr.GetTriggers()
+	if _, ok := trgrs["foo"]; ok {
+		t.Errorf("failed Reload - expected trigger foo to be removed")
+	}
+	if _, ok := trgrs["bar"]; !ok {
+		t.Errorf("failed Reload - expected trigger bar to be added")
+	}
+	if objs := wrkr.GetObjects(); len(objs) != 2 {
+		t.Errorf("failed Reload - expected 2 objects, got %d", len(objs))
+	}
+	if e := wrkr.entries["abc"]; e == nil || !e.past.Equal(tick) {
+		t.Errorf("failed Reload - expected processed time of abc to be kept, got %v", e)
+	}
+	if e := wrkr.entries["def"]; e == nil || !e.past.After(tick) {
+		t.Errorf("failed Reload - expected new object def to start now, got %v", e)
+	}
+}
+
+func TestReloadRunning(t *testing.T) {
+	wrkr := &worker{interval: time.Hour, triggers: map[string]trigger.Trigger{}}
+	wrkr.InitObjects()
+	scnrs := []*mockScanner{
+		{stop: make(chan bool)},
+		{stop: make(chan bool)},
+		{stop: make(chan bool)},
+	}
+	wrkr.AddScanner(scnrs[0])
+	wrkr.running = true
+	go wrkr.watch(wrkr.initWatchers())
+
+	done := make(chan bool)
+	go func() {
+		wrkr.Reload([]scanner.Scanner{scnrs[1]}, map[string]trigger.Trigger{})
+		wrkr.Reload([]scanner.Scanner{scnrs[2]}, map[string]trigger.Trigger{})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("failed Reload - reloading twice did not return")
+	}
+	wrkr.StopWatch()
+
+	for i, scnr := range scnrs {
+		select {
+		case <-scnr.stop:
+		case <-time.After(5 * time.Second):
+			t.Errorf("failed test %d - watcher of scanner was not stopped", i)
+		}
+	}
+}
+
+func TestScannerKey(t *testing.T) {
+	s1, _ := schedule.New("Mon-Fri 9:00 replicas=1")
+	s2, _ := schedule.New("Mon-Fri 18:00 replicas=0")
+	tests := []struct {
+		a     scanner.Config
+		b     scanner.Config
+		equal bool
+	}{
+		{
+			a:     scanner.Config{Namespace: "dev", Schedule: []*schedule.Schedule{s1}},
+			b:     scanner.Config{Namespace: "dev", Schedule: []*schedule.Schedule{s1.Copy()}},
+			equal: true,
+		},
+		{
+			a:     scanner.Config{Namespace: "dev", Schedule: []*schedule.Schedule{s1}},
+			b:     scanner.Config{Namespace: "dev", Schedule: []*schedule.Schedule{s1, s2}},
+			equal: false,
+		},
+		{
+			a:     scanner.Config{Namespace: "dev", Label: "app=shell"},
+			b:     scanner.Config{Namespace: "dev"},
+			equal: false,
+		},
+	}
+	for i, tst := range tests {
+		eq := scannerKey(tst.a) == scannerKey(tst.b)
+		if eq != tst.equal {
+			t.Errorf("failed test %d - expected equal=%v, got %v", i, tst.equal, eq)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/joyrex2001/nightshift/internal/schedule"
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+const (
+	outcomeQueued    = "queued"
+	outcomeCoalesced = "coalesced"
+	outcomeDropped   = "dropped"
+	outcomeRejected  = "rejected"
+)
+
+// runner keeps track of the queued and running executions of each trigger,
+// and limits the number of executions of a trigger that run at the same time.
+type runner struct {
+	m       sync.Mutex
+	started bool
+	stopped bool
+	done    chan bool
+	queues  map[string]*triggerQueue
+}
+
+// triggerQueue contains the queued executions of a single trigger, and the
+// number of its executions that are running. The inflight set contains the
+// event objects that are either queued or running, which is used to coalesce
+// executions for the same event.
+type triggerQueue struct {
+	policy   trigger.QueuePolicy
+	running  int
+	pending  []*triggerJob
+	inflight map[string]bool
+}
+
+// newRunner will instantiate a new runner, which will not start any
+// executions until it is started.
+func newRunner() *runner {
+	return &runner{
+		done:   make(chan bool),
+		queues: map[string]*triggerQueue{},
+	}
+}
+
+// start will allow the runner to start executions.
+func (r *runner) start() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.started = true
+}
+
+// stop will prevent the runner from starting new executions, and release
+// anything waiting for the runner to stop. Stopping a runner that has been
+// stopped already has no effect.
+func (r *runner) stop() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.started = false
+	if !r.stopped {
+		r.stopped = true
+		close(r.done)
+	}
+}
+
+// eventKeys will return the keys that identify the objects of the given
+// event in the inflight set of a trigger queue. An event without objects is
+// identified by its time, and parameters, only.
+func eventKeys(evt trigger.Event) []string {
+	at := fmt.Sprintf("%d", evt.Time.UnixNano())
+	if len(evt.Params) > 0 {
+		at += schedule.Invocation{Params: evt.Params}.String()
+	}
+	if len(evt.Objects) == 0 {
+		return []string{at}
+	}
+	keys := []string{}
+	for _, obj := range evt.Objects {
+		id := obj.UID
+		if id == "" {
+			id = obj.Namespace + "/" + obj.Name
+		}
+		keys = append(keys, at+"/"+id)
+	}
+	return keys
+}
+
+// enqueue will add an execution of the trigger with given id for given event
+// to its queue, and return the outcome. Objects that are already part of a
+// queued or running execution for the same event are left out, and if the
+// trigger already has a queued execution for the same event, the objects are
+// merged into that execution. If the queue is full, the overflow policy
+// determines if the execution is dropped, rejected or coalesced with the last
+// queued execution with the same parameters; if there is no such execution,
+// it is dropped.
+func (r *runner) enqueue(id string, evt trigger.Event, policy trigger.QueuePolicy) string {
+	r.m.Lock()
+	defer r.m.Unlock()
+	q, ok := r.queues[id]
+	if !ok {
+		q = &triggerQueue{inflight: map[string]bool{}}
+		r.queues[id] = q
+	}
+	q.policy = policy
+
+	keys := []string{}
+	objs := []trigger.EventObject{}
+	for i, key := range eventKeys(evt) {
+		if q.inflight[key] {
+			continue
+		}
+		keys = append(keys, key)
+		if len(evt.Objects) > 0 {
+			objs = append(objs, evt.Objects[i])
+		}
+	}
+	if len(keys) == 0 {
+		return outcomeCoalesced
+	}
+	evt.Objects = objs
+
+	var job *triggerJob
+	for _, pending := range q.pending {
+		if pending.event.Time.Equal(evt.Time) && sameParams(pending.event, evt) {
+			job = pending
+		}
+	}
+	if job == nil && len(q.pending) >= policy.QueueSize {
+		switch policy.Overflow {
+		case trigger.OverflowDrop:
+			return outcomeDropped
+		case trigger.OverflowReject:
+			return outcomeRejected
+		}
+		for _, pending := range q.pending {
+			if sameParams(pending.event, evt) {
+				job = pending
+			}
+		}
+		if job == nil {
+			return outcomeDropped
+		}
+	}
+
+	for _, key := range keys {
+		q.inflight[key] = true
+	}
+	if job != nil {
+		job.event.Objects = append(job.event.Objects, evt.Objects...)
+		job.keys = append(job.keys, keys...)
+		return outcomeCoalesced
+	}
+	q.pending = append(q.pending, &triggerJob{id: id, event: evt, keys: keys})
+	return outcomeQueued
+}
+
+// sameParams will return true if the given events have the same parameters.
+func sameParams(a, b trigger.Event) bool {
+	return schedule.Invocation{Params: a.Params}.String() == schedule.Invocation{Params: b.Params}.String()
+}
+
+// next will return the queued executions that can be started, without
+// exceeding the concurrency of each trigger, and mark them as running. It
+// will return nothing if the runner is not started.
+func (r *runner) next() []*triggerJob {
+	r.m.Lock()
+	defer r.m.Unlock()
+	res := []*triggerJob{}
+	if !r.started {
+		return res
+	}
+	for _, q := range r.queues {
+		for len(q.pending) > 0 && q.running < q.policy.Concurrency {
+			res = append(res, q.pending[0])
+			q.pending = q.pending[1:]
+			q.running++
+		}
+	}
+	return res
+}
+
+// finish will mark the given execution as finished.
+func (r *runner) finish(job *triggerJob) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	q, ok := r.queues[job.id]
+	if !ok {
+		return
+	}
+	q.running--
+	for _, key := range job.keys {
+		delete(q.inflight, key)
+	}
+}
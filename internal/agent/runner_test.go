@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+func TestEnqueue(t *testing.T) {
+	t1 := time.Unix(1, 0)
+	t2 := time.Unix(2, 0)
+	t3 := time.Unix(3, 0)
+	evt := func(at time.Time, names ...string) trigger.Event {
+		e := trigger.Event{Time: at}
+		for _, n := range names {
+			e.Objects = append(e.Objects, trigger.EventObject{UID: n, Name: n})
+		}
+		return e
+	}
+	tests := []struct {
+		overflow string
+		events   []trigger.Event
+		outcomes []string
+		pending  []int
+	}{
+		{
+			overflow: trigger.OverflowDrop,
+			events:   []trigger.Event{evt(t1, "a"), evt(t1, "b"), evt(t1, "a")},
+			outcomes: []string{outcomeQueued, outcomeCoalesced, outcomeCoalesced},
+			pending:  []int{2},
+		},
+		{
+			overflow: trigger.OverflowDrop,
+			events:   []trigger.Event{evt(t1, "a"), evt(t2, "a"), evt(t3, "a")},
+			outcomes: []string{outcomeQueued, outcomeQueued, outcomeDropped},
+			pending:  []int{1, 1},
+		},
+		{
+			overflow: trigger.OverflowReject,
+			events:   []trigger.Event{evt(t1), evt(t2), evt(t3), evt(t2)},
+			outcomes: []string{outcomeQueued, outcomeQueued, outcomeRejected, outcomeCoalesced},
+			pending:  []int{0, 0},
+		},
+		{
+			overflow: trigger.OverflowCoalesce,
+			events:   []trigger.Event{evt(t1, "a"), evt(t2, "a"), evt(t3, "a", "b"), evt(t3, "b")},
+			outcomes: []string{outcomeQueued, outcomeQueued, outcomeCoalesced, outcomeCoalesced},
+			pending:  []int{1, 3},
+		},
+	}
+	for i, tst := range tests {
+		r := newRunner()
+		policy := trigger.QueuePolicy{Concurrency: 1, QueueSize: 2, Overflow: tst.overflow}
+		for j, e := range tst.events {
+			if out := r.enqueue("trigger1", e, policy); out != tst.outcomes[j] {
+				t.Errorf("failed test %d/%d - expected %s, got %s", i, j, tst.outcomes[j], out)
+			}
+		}
+		q := r.queues["trigger1"]
+		if len(q.pending) != len(tst.pending) {
+			t.Errorf("failed test %d - expected %d queued executions, got %d", i, len(tst.pending), len(q.pending))
+			continue
+		}
+		for j, n := range tst.pending {
+			if len(q.pending[j].event.Objects) != n {
+				t.Errorf("failed test %d - expected %d objects in execution %d, got %d", i, n, j, len(q.pending[j].event.Objects))
+			}
+		}
+	}
+}
+
+func TestEnqueueRunning(t *testing.T) {
+	r := newRunner()
+	r.start()
+	policy := trigger.QueuePolicy{Concurrency: 1, QueueSize: 10, Overflow: trigger.OverflowCoalesce}
+	evt := trigger.Event{Time: time.Unix(1, 0), Objects: []trigger.EventObject{{UID: "a"}}}
+	r.enqueue("trigger1", evt, policy)
+	jobs := r.next()
+	if len(jobs) != 1 {
+		t.Fatalf("failed test - expected 1 started execution, got %d", len(jobs))
+	}
+	if out := r.enqueue("trigger1", evt, policy); out != outcomeCoalesced {
+		t.Errorf("failed test - expected running execution to be coalesced, got %s", out)
+	}
+	r.finish(jobs[0])
+	if out := r.enqueue("trigger1", evt, policy); out != outcomeQueued {
+		t.Errorf("failed test - expected finished execution to be queued again, got %s", out)
+	}
+}
+
+// blockingTrigger is a trigger that will block until it is released, and
+// keeps track of the number of executions that run at the same time.
+type blockingTrigger struct {
+	m       sync.Mutex
+	cfg     trigger.Config
+	release chan bool
+	started chan bool
+	done    chan bool
+	running int
+	max     int
+	exc     int
+}
+
+func (b *blockingTrigger) SetConfig(c trigger.Config) { b.cfg = c }
+func (b *blockingTrigger) GetConfig() trigger.Config  { return b.cfg }
+func (b *blockingTrigger) Execute(evt trigger.Event) error {
+	b.m.Lock()
+	b.running++
+	if b.running > b.max {
+		b.max = b.running
+	}
+	b.m.Unlock()
+	b.started <- true
+	<-b.release
+	b.m.Lock()
+	b.running--
+	b.exc++
+	b.m.Unlock()
+	b.done <- true
+	return nil
+}
+
+func TestTriggerConcurrency(t *testing.T) {
+	slow := &blockingTrigger{
+		release: make(chan bool),
+		started: make(chan bool, 4),
+		done:    make(chan bool, 4),
+		cfg:     trigger.Config{Settings: map[string]string{"concurrency": "2"}},
+	}
+	fast := &mockTrigger{done: make(chan trigger.Event, 1)}
+	agent := &worker{runner: newRunner()}
+	agent.triggers = map[string]trigger.Trigger{"slow": slow, "fast": fast}
+	go agent.StartTrigger()
+	defer agent.StopTrigger()
+
+	for i := 0; i < 4; i++ {
+		agent.queueTrigger("slow", trigger.Event{Time: time.Unix(int64(i), 0)})
+	}
+	agent.queueTrigger("fast", trigger.Event{})
+	select {
+	case <-fast.done:
+	case <-time.After(5 * time.Second):
+		t.Errorf("failed test - expected fast trigger not to wait for slow trigger")
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-slow.started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("failed test - expected 2 concurrent executions to start, got %d", i)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		slow.release <- true
+	}
+	for i := 0; i < 4; i++ {
+		select {
+		case <-slow.done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("failed test - expected 4 executions, got %d", i)
+		}
+	}
+	slow.m.Lock()
+	defer slow.m.Unlock()
+	if slow.exc != 4 {
+		t.Errorf("failed test - expected 4 executions, got %d", slow.exc)
+	}
+	if slow.max != 2 {
+		t.Errorf("failed test - expected at most 2 concurrent executions, got %d", slow.max)
+	}
+}
+
+func TestStopRunner(t *testing.T) {
+	r := newRunner()
+	r.start()
+	r.stop()
+	r.stop()
+	select {
+	case <-r.done:
+	default:
+		t.Errorf("failed test - expected runner to be stopped")
+	}
+	if jobs := r.next(); len(jobs) != 0 {
+		t.Errorf("failed test - expected no executions after stop, got %v", jobs)
+	}
+}
@@ -2,16 +2,21 @@ package agent
 
 import (
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
 
+	"github.com/joyrex2001/nightshift/internal/activity"
 	"github.com/joyrex2001/nightshift/internal/metrics"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/schedule"
+	"github.com/joyrex2001/nightshift/internal/trigger"
 )
 
-const scaleInterval = 30 * time.Second
+// maxSleep is the maximum time the scale loop will sleep, so changes of the
+// wall clock (e.g. after a suspend, or a leap in ntp time) are picked up.
+const maxSleep = 15 * time.Minute
 
 type event struct {
 	at      time.Time
@@ -20,13 +25,26 @@ type event struct {
 	restore bool
 }
 
-// StartScale will call the scale method on a predefined interval.
+// StartScale will run the scale loop. It will sleep until the earliest event
+// on the timeline, and process the objects that are due. It will recalculate
+// the time to sleep when the timeline changes.
 func (a *worker) StartScale() {
+	a.scaleObjects()
 	for {
-		tmr := time.NewTimer(scaleInterval)
+		sleep := maxSleep
+		if next, ok := a.nextWakeup(); ok {
+			sleep = time.Until(next)
+			if sleep > maxSleep {
+				sleep = maxSleep
+			}
+		}
+		tmr := time.NewTimer(sleep)
 		select {
 		case <-a.done:
+			tmr.Stop()
 			return
+		case <-a.wake:
+			tmr.Stop()
 		case <-tmr.C:
 			a.scaleObjects()
 		}
@@ -38,37 +56,236 @@ func (a *worker) StopScale() {
 	a.done <- true
 }
 
-// Scale will process all scanned objects and scale them accordingly.
+// scaleResult is the aggregated result of processing the events of a single
+// object.
+type scaleResult struct {
+	uid      string
+	events   int
+	errors   int
+	triggers []triggerRef
+	retry    *retry
+}
+
+// retry is a scale event that has been vetoed by a before hook, and should be
+// processed again at the given time. The retries is the number of times the
+// event has been retried, including this retry.
+type retry struct {
+	event   time.Time
+	at      time.Time
+	retries int
+}
+
+// Scale will process the objects that have events due, and scale them
+// accordingly. The objects are scaled concurrently by a pool of workers.
+// Triggers with a schedule of their own that are due are queued as well.
 func (a *worker) scaleObjects() {
-	trgrs := []string{}
 	glog.V(4).Info("Scaling resources start...")
-	a.now = time.Now()
-	for _, obj := range a.GetObjects() {
-		for _, e := range a.getEvents(obj) {
-			glog.V(4).Infof("Scale event: %v", e)
-			trgrs = append(trgrs, e.sched.GetTriggers()...)
-			a.handleState(e)
-			a.scale(e)
+	now := time.Now()
+	objs := []due{}
+	trgrs := []triggerRef{}
+	for _, d := range a.popDue(now) {
+		if d.obj == nil {
+			trgrs = append(trgrs, getScheduledTriggerRefs(d, now)...)
+			continue
+		}
+		objs = append(objs, d)
+	}
+	results := a.runScaleWorkers(objs, now)
+	failed := 0
+	for _, res := range results {
+		trgrs = append(trgrs, res.triggers...)
+		if res.errors > 0 {
+			failed++
+		}
+		if res.retry != nil {
+			a.retryEvent(res.uid, *res.retry)
 		}
 	}
 	a.queueTriggers(trgrs)
-	a.past = a.now
-	glog.V(4).Info("Scaling resources finished...")
+	metrics.ObserveScaleTick(time.Since(now))
+	glog.V(4).Infof("Scaling resources finished, %d objects processed, %d failed...", len(results), failed)
+}
+
+// runScaleWorkers will process the events of the given objects with a pool of
+// workers. The events of a single object are processed by the same worker in
+// chronological order. The results are returned in the order of the given
+// objects.
+func (a *worker) runScaleWorkers(dues []due, now time.Time) []scaleResult {
+	results := make([]scaleResult, len(dues))
+	n := a.workers
+	if n > len(dues) {
+		n = len(dues)
+	}
+	if n < 1 {
+		n = 1
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = a.scaleObject(dues[idx], now)
+			}
+		}()
+	}
+	for idx := range dues {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// scaleObject will process all events of given object that are due. If a
+// before hook of an event fails, the event is skipped altogether, or retried
+// later, according to the veto policy of the hook. If the event is retried,
+// the events after it are processed after the retry as well.
+func (a *worker) scaleObject(d due, now time.Time) scaleResult {
+	res := scaleResult{uid: d.obj.UID, triggers: []triggerRef{}}
+	for i, e := range getEvents(d.obj, d.past, now) {
+		glog.V(4).Infof("Scale event: %v", e)
+		res.events++
+		old := e.obj.Replicas
+		if verr := a.runBeforeHooks(e); verr != nil {
+			metrics.Increase("hook_veto")
+			activity.Add(newRecord(e, "scale", time.Now(), verr))
+			res.errors++
+			// only the first event can be a retry of a vetoed event
+			retries := 0
+			if i == 0 {
+				retries = d.retries
+			}
+			if policy := a.vetoPolicy(verr.hook); policy.ShouldRetry(retries) {
+				glog.Errorf("Retrying scale of %s/%s in %s: %s", e.obj.Namespace, e.obj.Name, policy.Delay, verr)
+				res.retry = &retry{event: e.at, at: now.Add(policy.Delay), retries: retries + 1}
+				break
+			}
+			glog.Errorf("Skipping scale of %s/%s: %s", e.obj.Namespace, e.obj.Name, verr)
+			continue
+		}
+		if err := a.handleState(e); err != nil {
+			res.errors++
+		}
+		if err := a.scale(e); err != nil {
+			res.errors++
+		} else {
+			a.runAfterHooks(e, old)
+		}
+		res.triggers = append(res.triggers, newTriggerRefs(e, old)...)
+	}
+	return res
+}
+
+// newTriggerRefs will return references to the triggers of given event, with
+// the details of the object that has been scaled from the given number of
+// replicas.
+func newTriggerRefs(e *event, old int) []triggerRef {
+	refs := []triggerRef{}
+	for _, inv := range e.sched.GetTriggers() {
+		refs = append(refs, triggerRef{
+			id:     inv.Id,
+			params: inv.Params,
+			at:     e.at,
+			sched:  e.sched.Description,
+			obj: &trigger.EventObject{
+				Namespace:   e.obj.Namespace,
+				Name:        e.obj.Name,
+				UID:         e.obj.UID,
+				ScannerId:   e.obj.ScannerId,
+				Schedule:    e.sched.Description,
+				OldReplicas: old,
+				NewReplicas: e.obj.Replicas,
+				NextScaleUp: nextScaleUp(e.obj.Schedule, e.at),
+			},
+		})
+	}
+	return refs
+}
+
+// nextScaleUp will return the time of the first event after given time in
+// the given schedules that will scale up, or restore, the object. It will
+// return nil if there is no such event.
+func nextScaleUp(scheds []*schedule.Schedule, after time.Time) *time.Time {
+	// schedules repeat every week, so looking one week ahead is sufficient
+	for _, e := range eventsBetween(nil, scheds, after.Add(time.Nanosecond), after.AddDate(0, 0, 7)) {
+		if isScaleUp(e.sched) {
+			return &e.at
+		}
+	}
+	return nil
+}
+
+// isScaleUp will return true if the given schedule will scale up, or restore,
+// the object.
+func isScaleUp(s *schedule.Schedule) bool {
+	if state, _ := s.GetState(); state == schedule.RestoreState {
+		return true
+	}
+	repl, err := s.GetReplicas()
+	return err == nil && s.HasReplicas() && repl > 0
+}
+
+// getScheduledTriggerRefs will return references to the trigger of given due
+// scheduled trigger, for each of its events between the time it was processed
+// last, and now.
+func getScheduledTriggerRefs(d due, now time.Time) []triggerRef {
+	refs := []triggerRef{}
+	for _, e := range eventsBetween(nil, d.sched, d.past, now) {
+		refs = append(refs, triggerRef{id: d.trigger, at: e.at, sched: e.sched.Description})
+	}
+	return refs
 }
 
 // getEvents will return the events in chronological order that have to be
-// done for the given object in the current tick.
-func (a *worker) getEvents(obj *scanner.Object) []*event {
+// done for the given object between the time it was processed last (past),
+// and now. If the object is snoozed, no events will be returned. If the
+// snooze expired, the object will be reconciled to its current scheduled
+// state instead.
+func getEvents(obj *scanner.Object, past, now time.Time) []*event {
+	if obj.IsSnoozed(now) {
+		glog.V(4).Infof("Skipping events for snoozed %s/%s", obj.Namespace, obj.Name)
+		return []*event{}
+	}
+	if obj.SnoozeUntil != nil && past.Before(*obj.SnoozeUntil) {
+		glog.V(4).Infof("Snooze expired for %s/%s", obj.Namespace, obj.Name)
+		return getReconcileEvents(obj, now)
+	}
+	return getEventsBetween(obj, past, now)
+}
+
+// getReconcileEvents will return the last event that should have been done
+// for the given object according to its schedule, as an array of events. If
+// there is no such event, an empty array is returned.
+func getReconcileEvents(obj *scanner.Object, now time.Time) []*event {
+	ev := getEventsBetween(obj, now.AddDate(0, 0, -7), now)
+	if len(ev) == 0 {
+		return ev
+	}
+	return ev[len(ev)-1:]
+}
+
+// getEventsBetween will return the events in chronological order that occur
+// between the given times (inclusive) for the given object.
+func getEventsBetween(obj *scanner.Object, past, now time.Time) []*event {
+	return eventsBetween(obj, obj.Schedule, past, now)
+}
+
+// eventsBetween will return the events in chronological order that occur
+// between the given times (inclusive) in the given schedules, for the given
+// object.
+func eventsBetween(obj *scanner.Object, scheds []*schedule.Schedule, past, now time.Time) []*event {
 	var err error
 	ev := []*event{}
-	for _, s := range obj.Schedule {
-		for next := a.past; !next.After(a.now); next = next.AddDate(0, 0, 1) {
+	for _, s := range scheds {
+		for next := past; !next.After(now); next = next.AddDate(0, 0, 1) {
 			next, err = s.GetNextTrigger(next)
 			if err != nil {
 				glog.Errorf("Error processing trigger: %s", err)
 				continue
 			}
-			if a.now.After(next) || a.now == next {
+			if now.After(next) || now == next {
 				ev = append(ev, &event{next, obj, s, false})
 			}
 		}
@@ -79,17 +296,25 @@ func (a *worker) getEvents(obj *scanner.Object) []*event {
 }
 
 // handleState will save or restore state if this is defined in the schedule.
-func (a *worker) handleState(e *event) {
+// It will return an error if the state could not be saved.
+func (a *worker) handleState(e *event) error {
 	state, err := e.sched.GetState()
 	if err != nil {
 		glog.Errorf("Error scaling deployment: %s", err)
-		return
+		return err
 	}
 	// Save the current number of pods
 	if state == schedule.SaveState {
-		if err := e.obj.SaveState(); err != nil {
+		start := time.Now()
+		err := e.obj.SaveState()
+		rec := newRecord(e, "save", start, err)
+		if e.obj.State != nil {
+			rec.NewReplicas = e.obj.State.Replicas
+		}
+		activity.Add(rec)
+		if err != nil {
 			glog.Errorf("Error saving state: %s", err)
-			return
+			return err
 		}
 	}
 	// Restore the number of pods previously saved, and update object with the
@@ -97,24 +322,37 @@ func (a *worker) handleState(e *event) {
 	if state == schedule.RestoreState {
 		if e.obj.State == nil {
 			glog.Errorf("No state available on %s/%s", e.obj.Namespace, e.obj.Name)
-			return
+			return nil
 		}
 		e.restore = true
 	}
+	return nil
 }
 
-// scale will scale according to the event details.
-func (a *worker) scale(e *event) {
+// scale will scale according to the event details. It will return an error
+// if scaling failed.
+func (a *worker) scale(e *event) error {
+	start := time.Now()
+	old := e.obj.Replicas
 	// restore state
 	if e.restore {
 		repl := e.obj.State.Replicas
-		if err := e.obj.Scale(repl); err != nil {
+		err := e.obj.Scale(repl)
+		if err != nil {
 			glog.Errorf("Error scaling deployment: %s", err)
 			metrics.Increase("scale_error")
 		}
 		metrics.Increase("scale")
 		metrics.SetReplicas(e.obj.Namespace, e.obj.ScannerId, repl)
-		return
+		rec := newRecord(e, "restore", start, err)
+		rec.OldReplicas, rec.NewReplicas = old, repl
+		activity.Add(rec)
+		return err
+	}
+	// schedules without replicas will only handle state and triggers
+	if !e.sched.HasReplicas() {
+		glog.V(4).Infof("No replicas in schedule '%s', not scaling %s/%s", e.sched.Description, e.obj.Namespace, e.obj.Name)
+		return nil
 	}
 	// regular scaling
 	repl, err := e.sched.GetReplicas()
@@ -127,4 +365,27 @@ func (a *worker) scale(e *event) {
 		metrics.Increase("scale_error")
 		glog.Errorf("Error scaling deployment: %s", err)
 	}
+	rec := newRecord(e, "scale", start, err)
+	rec.OldReplicas, rec.NewReplicas = old, repl
+	activity.Add(rec)
+	return err
+}
+
+// newRecord will return an activity record for given event and action.
+func newRecord(e *event, action string, start time.Time, err error) activity.Record {
+	state, _ := e.sched.GetState()
+	return activity.Record{
+		Time:        start,
+		Action:      action,
+		Namespace:   e.obj.Namespace,
+		Object:      e.obj.Name,
+		UID:         e.obj.UID,
+		ScannerId:   e.obj.ScannerId,
+		Schedule:    e.sched.Description,
+		OldReplicas: e.obj.Replicas,
+		NewReplicas: e.obj.Replicas,
+		State:       string(state),
+		Duration:    time.Since(start),
+		Error:       activity.ErrorString(err),
+	}
 }
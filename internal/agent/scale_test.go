@@ -2,6 +2,8 @@ package agent
 
 import (
 	"fmt"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -68,9 +70,6 @@ func TestGetEvents(t *testing.T) {
 	}
 
 	for i, tst := range tests {
-		agt := &worker{}
-		agt.past = tst.past
-		agt.now = tst.now
 		obj := &scanner.Object{}
 		obj.Schedule = []*schedule.Schedule{}
 		for _, s := range tst.sched {
@@ -81,7 +80,7 @@ func TestGetEvents(t *testing.T) {
 				obj.Schedule = append(obj.Schedule, sc)
 			}
 		}
-		evts := agt.getEvents(obj)
+		evts := getEvents(obj, tst.past, tst.now)
 		for j, evt := range evts {
 			fmt.Printf("[%02d] %s\n", j, evt.at)
 		}
@@ -97,6 +96,71 @@ func TestGetEvents(t *testing.T) {
 	}
 }
 
+func TestGetEventsSnooze(t *testing.T) {
+	snooze := func(t time.Time) *time.Time { return &t }
+	tests := []struct {
+		past   time.Time
+		now    time.Time
+		snooze *time.Time
+		events []time.Time
+	}{
+		{
+			past:   time.Date(2019, 3, 4, 17, 59, 0, 0, time.UTC), // monday
+			now:    time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC),
+			snooze: nil,
+			events: []time.Time{
+				time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			past:   time.Date(2019, 3, 4, 17, 59, 0, 0, time.UTC),
+			now:    time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC),
+			snooze: snooze(time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)),
+			events: []time.Time{},
+		},
+		{
+			past:   time.Date(2019, 3, 4, 22, 59, 0, 0, time.UTC),
+			now:    time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC),
+			snooze: snooze(time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)),
+			events: []time.Time{
+				time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			past:   time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC),
+			now:    time.Date(2019, 3, 4, 23, 1, 0, 0, time.UTC),
+			snooze: snooze(time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)),
+			events: []time.Time{},
+		},
+		{
+			past:   time.Date(2019, 3, 5, 7, 59, 0, 0, time.UTC), // tuesday
+			now:    time.Date(2019, 3, 5, 8, 0, 0, 0, time.UTC),
+			snooze: snooze(time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)),
+			events: []time.Time{
+				time.Date(2019, 3, 5, 8, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for i, tst := range tests {
+		obj := &scanner.Object{SnoozeUntil: tst.snooze}
+		for _, s := range []string{"Mon-Fri 8:00 replicas=1", "Mon-Fri 18:00 replicas=0"} {
+			sc, _ := schedule.New(s)
+			obj.Schedule = append(obj.Schedule, sc)
+		}
+		evts := getEvents(obj, tst.past, tst.now)
+		if len(evts) != len(tst.events) {
+			t.Errorf("failed test %d - invalid number of events, expected: %v, got %v", i, len(tst.events), len(evts))
+			continue
+		}
+		for j, evt := range evts {
+			if evt.at != tst.events[j] {
+				t.Errorf("failed test %d.%d - invalid events, expected: %v, got %v", i, j, tst.events[j], evt.at)
+			}
+		}
+	}
+}
+
 func TestHandleStateScale(t *testing.T) {
 	mock := &mockScanner{}
 	scanner.RegisterModule("scanner", getScannerFactory("scanner", mock))
@@ -129,6 +193,13 @@ func TestHandleStateScale(t *testing.T) {
 			save:    true,
 			scale:   2,
 		},
+		{
+			sched:   "Mon-Fri 8:00 state=save trigger=backup",
+			obj:     &scanner.Object{},
+			restore: false,
+			save:    true,
+			scale:   -1,
+		},
 	}
 
 	for i, tst := range tests {
@@ -137,6 +208,7 @@ func TestHandleStateScale(t *testing.T) {
 		sc, _ := schedule.New(tst.sched)
 		tst.obj.Schedule = []*schedule.Schedule{sc}
 		mock.save = false
+		mock.scale = -1
 
 		evt := &event{
 			obj:     tst.obj,
@@ -159,3 +231,114 @@ func TestHandleStateScale(t *testing.T) {
 	}
 
 }
+
+// slowScanner is a mock scanner that records the scale operations per object,
+// and the maximum number of concurrent scale operations.
+type slowScanner struct {
+	mockScanner
+	m       sync.Mutex
+	scaled  map[string][]int
+	running int
+	max     int
+}
+
+func (m *slowScanner) Scale(obj *scanner.Object, r int) error {
+	m.m.Lock()
+	m.running++
+	if m.running > m.max {
+		m.max = m.running
+	}
+	m.m.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	m.m.Lock()
+	defer m.m.Unlock()
+	m.running--
+	m.scaled[obj.UID] = append(m.scaled[obj.UID], r)
+	if obj.UID == "fail" {
+		return fmt.Errorf("scale failed")
+	}
+	return nil
+}
+
+func TestRunScaleWorkers(t *testing.T) {
+	tests := []struct {
+		workers int
+		max     int
+	}{
+		{workers: 1, max: 1},
+		{workers: 2, max: 2},
+		{workers: 10, max: 4},
+	}
+	past := time.Date(2019, 3, 4, 0, 0, 0, 0, time.UTC) // monday
+	now := time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)
+	for i, tst := range tests {
+		mock := &slowScanner{scaled: map[string][]int{}}
+		dues := []due{}
+		for _, uid := range []string{"a", "b", "c", "fail"} {
+			obj := newScheduledObject(uid, "Mon-Fri 18:00 replicas=0", "Mon-Fri 8:00 replicas=1")
+			obj.Type = "slowscanner"
+			dues = append(dues, due{obj: obj, past: past})
+		}
+		scanner.RegisterModule("slowscanner", func() (scanner.Scanner, error) { return mock, nil })
+
+		wrkr := &worker{}
+		wrkr.SetScaleWorkers(tst.workers)
+		res := wrkr.runScaleWorkers(dues, now)
+
+		if mock.max != tst.max {
+			t.Errorf("failed test %d - expected %d concurrent scale operations, got %d", i, tst.max, mock.max)
+		}
+		if len(res) != len(dues) {
+			t.Errorf("failed test %d - expected %d results, got %d", i, len(dues), len(res))
+			continue
+		}
+		for j, r := range res {
+			uid := dues[j].obj.UID
+			if r.uid != uid || r.events != 2 {
+				t.Errorf("failed test %d.%d - unexpected result %v", i, j, r)
+			}
+			if seq := mock.scaled[uid]; !reflect.DeepEqual(seq, []int{1, 0}) {
+				t.Errorf("failed test %d.%d - expected events in order [1 0], got %v", i, j, seq)
+			}
+			if (r.errors > 0) != (uid == "fail") {
+				t.Errorf("failed test %d.%d - unexpected errors %d", i, j, r.errors)
+			}
+		}
+	}
+}
+
+func TestNextScaleUp(t *testing.T) {
+	monday := time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		scheds []string
+		next   *time.Time
+	}{
+		{
+			scheds: []string{"Mon-Fri 8:00 replicas=1", "Mon-Fri 18:00 replicas=0"},
+			next:   timePtr(time.Date(2019, 3, 5, 8, 0, 0, 0, time.UTC)),
+		},
+		{
+			scheds: []string{"Mon 18:00 replicas=0", "Mon 18:00 replicas=2"},
+			next:   timePtr(time.Date(2019, 3, 11, 18, 0, 0, 0, time.UTC)),
+		},
+		{
+			scheds: []string{"Fri 18:00 replicas=0 state=save", "Mon 7:00 state=restore"},
+			next:   timePtr(time.Date(2019, 3, 11, 7, 0, 0, 0, time.UTC)),
+		},
+		{
+			scheds: []string{"Mon-Fri 18:00 replicas=0", "Sat 9:00 trigger=report"},
+			next:   nil,
+		},
+	}
+	for i, tst := range tests {
+		obj := newScheduledObject("obj", tst.scheds...)
+		next := nextScaleUp(obj.Schedule, monday)
+		if (next == nil) != (tst.next == nil) || (next != nil && !next.Equal(*tst.next)) {
+			t.Errorf("failed test %d - expected %v, got %v", i, tst.next, next)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
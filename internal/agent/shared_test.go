@@ -1,6 +1,9 @@
 package agent
 
 import (
+	"sync"
+	"time"
+
 	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/trigger"
 )
@@ -10,7 +13,7 @@ type mockScanner struct {
 	id    int
 	scale int
 	save  bool
-	stop  bool
+	stop  chan bool
 	out   chan scanner.Event
 	objs  []*scanner.Object
 }
@@ -31,6 +34,10 @@ func (m *mockScanner) SaveState(obj *scanner.Object) (int, error) {
 	return 0, nil
 }
 
+func (m *mockScanner) Snooze(obj *scanner.Object, until *time.Time) error {
+	return nil
+}
+
 func (m *mockScanner) Scale(obj *scanner.Object, r int) error {
 	m.scale = r
 	return nil
@@ -38,7 +45,12 @@ func (m *mockScanner) Scale(obj *scanner.Object, r int) error {
 
 func (m *mockScanner) Watch(_stop chan bool) (chan scanner.Event, error) {
 	m.out = make(chan scanner.Event)
-	go func() { m.stop = <-_stop }()
+	go func() {
+		<-_stop
+		if m.stop != nil {
+			close(m.stop)
+		}
+	}()
 	return m.out, nil
 }
 
@@ -48,11 +60,16 @@ func getScannerFactory(typ string, m *mockScanner) scanner.Factory {
 	}
 }
 
-// mockTrigger is a generic mock for triggers
+// mockTrigger is a generic mock for triggers; if done is set, each executed
+// event is sent to it, so tests can wait for executions.
 type mockTrigger struct {
-	id  string
-	exc int
-	cfg trigger.Config
+	m    sync.Mutex
+	id   string
+	exc  int
+	err  error
+	evt  trigger.Event
+	cfg  trigger.Config
+	done chan trigger.Event
 }
 
 func (m *mockTrigger) SetConfig(c trigger.Config) {
@@ -63,9 +80,23 @@ func (m *mockTrigger) GetConfig() trigger.Config {
 	return m.cfg
 }
 
-func (m *mockTrigger) Execute() error {
+func (m *mockTrigger) Execute(evt trigger.Event) error {
+	m.m.Lock()
 	m.exc++
-	return nil
+	m.evt = evt
+	err := m.err
+	m.m.Unlock()
+	if m.done != nil {
+		m.done <- evt
+	}
+	return err
+}
+
+// executions will return the number of times the trigger was executed.
+func (m *mockTrigger) executions() int {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return m.exc
 }
 
 func getTriggerFactory(typ string, m *mockTrigger) trigger.Factory {
@@ -0,0 +1,263 @@
+package agent
+
+import (
+	"container/heap"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+// triggerPrefix is the prefix of the keys on the timeline of triggers that
+// have a schedule of their own.
+const triggerPrefix = "trigger:"
+
+// entry is an item on the timeline; it contains the time of the next
+// scheduled event of an object, and the time until which the events of the
+// object have been processed. If a vetoed event of the object should be
+// retried, the retry determines the time of the next event instead. Triggers
+// with a schedule of their own are on the timeline as well, with the trigger
+// id prefixed by triggerPrefix as uid.
+type entry struct {
+	uid   string
+	at    time.Time
+	past  time.Time
+	retry *retry
+	index int
+}
+
+// timeline is a min-heap that contains the next scheduled event of each
+// object, which allows the scale loop to sleep until the earliest event,
+// instead of polling all objects at a fixed interval.
+type timeline []*entry
+
+// Len returns the length of the timeline, as required by the heap interface.
+func (tl timeline) Len() int {
+	return len(tl)
+}
+
+// Less compares the entries, and determines the order of the timeline, as
+// required by the heap interface.
+func (tl timeline) Less(i, j int) bool {
+	return tl[i].at.Before(tl[j].at)
+}
+
+// Swap will swap two entries on the timeline, as required by the heap
+// interface.
+func (tl timeline) Swap(i, j int) {
+	tl[i], tl[j] = tl[j], tl[i]
+	tl[i].index = i
+	tl[j].index = j
+}
+
+// Push will add an entry to the timeline.
+func (tl *timeline) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*tl)
+	*tl = append(*tl, e)
+}
+
+// Pop will remove the last entry from the timeline.
+func (tl *timeline) Pop() interface{} {
+	old := *tl
+	n := len(old)
+	e := old[n-1]
+	e.index = -1
+	*tl = old[0 : n-1]
+	return e
+}
+
+// nextEvent will return the time of the first event after given time for
+// given object. If the object is snoozed, this will be the end of the snooze,
+// so the object can be reconciled. It will return false if the object has no
+// upcoming events at all.
+func nextEvent(obj *scanner.Object, past time.Time) (time.Time, bool) {
+	if obj.SnoozeUntil != nil && past.Before(*obj.SnoozeUntil) {
+		return *obj.SnoozeUntil, true
+	}
+	return nextScheduled(obj.Schedule, past)
+}
+
+// nextScheduled will return the time of the first event after given time in
+// the given schedules. It will return false if there are no upcoming events.
+func nextScheduled(scheds []*schedule.Schedule, past time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, s := range scheds {
+		at, err := s.GetNextTrigger(past.Add(time.Nanosecond))
+		if err != nil {
+			glog.Errorf("Error processing trigger: %s", err)
+			continue
+		}
+		if !found || at.Before(next) {
+			next, found = at, true
+		}
+	}
+	return next, found
+}
+
+// since will return the time from which events should be processed for
+// objects that are not yet on the timeline. Before the first scale run this
+// is the configured catch-up time, afterwards objects start with a clean
+// slate.
+func (a *worker) since() time.Time {
+	if a.past.IsZero() {
+		return time.Now()
+	}
+	return a.past
+}
+
+// reschedule will recalculate the next event of the object with given uid,
+// and update its position on the timeline. Objects that are no longer known,
+// or that have no upcoming events, are removed from the timeline. It should
+// be called while holding the lock.
+func (a *worker) reschedule(uid string) {
+	if a.entries == nil {
+		a.entries = map[string]*entry{}
+	}
+	e, onTimeline := a.entries[uid]
+	past := a.since()
+	if onTimeline {
+		past = e.past
+	}
+	var at time.Time
+	found := false
+	if opq, ok := a.objects[uid]; ok && len(*opq) > 0 {
+		at, found = nextEvent((*opq)[0], past)
+		if onTimeline && e.retry != nil {
+			at, found = e.retry.at, true
+		}
+	} else if scheds, ok := a.triggerSchedule(uid); ok {
+		at, found = nextScheduled(scheds, past)
+	}
+	switch {
+	case !found && onTimeline:
+		heap.Remove(&a.timeline, e.index)
+		delete(a.entries, uid)
+	case found && onTimeline:
+		e.at = at
+		heap.Fix(&a.timeline, e.index)
+	case found:
+		e = &entry{uid: uid, at: at, past: past}
+		heap.Push(&a.timeline, e)
+		a.entries[uid] = e
+	}
+	a.notify()
+}
+
+// triggerKey will return the key on the timeline of the trigger with given
+// id.
+func triggerKey(id string) string {
+	return triggerPrefix + id
+}
+
+// triggerSchedule will return the schedule of the trigger of which the key on
+// the timeline is given, or false if the key does not refer to a trigger. It
+// should be called while holding the lock.
+func (a *worker) triggerSchedule(key string) ([]*schedule.Schedule, bool) {
+	if !strings.HasPrefix(key, triggerPrefix) {
+		return nil, false
+	}
+	trgr, ok := a.triggers[strings.TrimPrefix(key, triggerPrefix)]
+	if !ok {
+		return nil, false
+	}
+	return trgr.GetConfig().Schedule, true
+}
+
+// rebuildTimeline will reschedule all known objects and scheduled triggers,
+// and remove the entries of objects and triggers that are no longer known.
+// The processed time of entries that are already on the timeline is kept. It
+// should be called while holding the lock.
+func (a *worker) rebuildTimeline() {
+	for uid := range a.entries {
+		if _, ok := a.objects[uid]; !ok {
+			a.reschedule(uid)
+		}
+	}
+	for uid := range a.objects {
+		a.reschedule(uid)
+	}
+	for id := range a.triggers {
+		a.reschedule(triggerKey(id))
+	}
+}
+
+// nextWakeup will return the time of the earliest event on the timeline, or
+// false if the timeline is empty.
+func (a *worker) nextWakeup() (time.Time, bool) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	if len(a.timeline) == 0 {
+		return time.Time{}, false
+	}
+	return a.timeline[0].at, true
+}
+
+// due is an object, or a scheduled trigger, of which events should be
+// processed, together with the time until which its events were processed
+// already.
+type due struct {
+	obj     *scanner.Object
+	trigger string
+	sched   []*schedule.Schedule
+	past    time.Time
+	retries int
+}
+
+// popDue will return the objects and triggers that have events scheduled at
+// or before the given time, and will reschedule these objects on the timeline, as if their
+// events up to given time have been processed.
+func (a *worker) popDue(now time.Time) []due {
+	a.m.Lock()
+	defer a.m.Unlock()
+	res := []due{}
+	for len(a.timeline) > 0 && !a.timeline[0].at.After(now) {
+		e := a.timeline[0]
+		if opq, ok := a.objects[e.uid]; ok && len(*opq) > 0 {
+			d := due{obj: (*opq)[0].Copy(), past: e.past}
+			if e.retry != nil {
+				d.retries = e.retry.retries
+			}
+			res = append(res, d)
+		} else if scheds, ok := a.triggerSchedule(e.uid); ok {
+			id := strings.TrimPrefix(e.uid, triggerPrefix)
+			res = append(res, due{trigger: id, sched: scheds, past: e.past})
+		}
+		e.past = now
+		e.retry = nil
+		a.reschedule(e.uid)
+	}
+	a.past = time.Time{}
+	return res
+}
+
+// retryEvent will reschedule the object with given uid, so the vetoed event
+// of given retry, and the events after it, are processed again at the time of
+// the retry. Objects that are no longer on the timeline are not retried.
+func (a *worker) retryEvent(uid string, r retry) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	e, ok := a.entries[uid]
+	if !ok {
+		return
+	}
+	e.past = r.event
+	e.retry = &r
+	a.reschedule(uid)
+}
+
+// notify will wake up the scale loop, so it can recalculate the time it
+// should sleep. It will not block if the scale loop is already notified.
+func (a *worker) notify() {
+	if a.wake == nil {
+		return
+	}
+	select {
+	case a.wake <- true:
+	default:
+	}
+}
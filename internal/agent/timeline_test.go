@@ -0,0 +1,166 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+	"github.com/joyrex2001/nightshift/internal/trigger"
+)
+
+func newScheduledObject(uid string, scheds ...string) *scanner.Object {
+	obj := &scanner.Object{UID: uid, Schedule: []*schedule.Schedule{}}
+	for _, s := range scheds {
+		sc, _ := schedule.New(s)
+		obj.Schedule = append(obj.Schedule, sc)
+	}
+	return obj
+}
+
+func TestNextEvent(t *testing.T) {
+	snooze := time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)
+	tests := []struct {
+		obj   *scanner.Object
+		past  time.Time
+		next  time.Time
+		found bool
+	}{
+		{
+			obj:   newScheduledObject("a", "Mon-Fri 8:00 replicas=1", "Mon-Fri 18:00 replicas=0"),
+			past:  time.Date(2019, 3, 4, 9, 0, 0, 0, time.UTC), // monday
+			next:  time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC),
+			found: true,
+		},
+		{
+			obj:   newScheduledObject("a", "Mon-Fri 8:00 replicas=1", "Mon-Fri 18:00 replicas=0"),
+			past:  time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC),
+			next:  time.Date(2019, 3, 5, 8, 0, 0, 0, time.UTC),
+			found: true,
+		},
+		{
+			obj:   newScheduledObject("a", "Mon-Fri 8:00 replicas=1"),
+			past:  time.Date(2019, 3, 8, 9, 0, 0, 0, time.UTC), // friday
+			next:  time.Date(2019, 3, 11, 8, 0, 0, 0, time.UTC),
+			found: true,
+		},
+		{
+			obj:   newScheduledObject("a"),
+			past:  time.Date(2019, 3, 4, 9, 0, 0, 0, time.UTC),
+			found: false,
+		},
+		{
+			obj: func() *scanner.Object {
+				obj := newScheduledObject("a", "Mon-Fri 18:00 replicas=0")
+				obj.SnoozeUntil = &snooze
+				return obj
+			}(),
+			past:  time.Date(2019, 3, 4, 9, 0, 0, 0, time.UTC),
+			next:  snooze,
+			found: true,
+		},
+	}
+	for i, tst := range tests {
+		next, found := nextEvent(tst.obj, tst.past)
+		if found != tst.found {
+			t.Errorf("failed test %d - expected found %t, got %t", i, tst.found, found)
+		}
+		if found && !next.Equal(tst.next) {
+			t.Errorf("failed test %d - expected next %s, got %s", i, tst.next, next)
+		}
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-2 * time.Hour)
+	at := func(d time.Duration) string {
+		return now.Add(d).In(time.UTC).Format("Mon 15:04")
+	}
+	if err := schedule.SetTimeZone("UTC"); err != nil {
+		t.Fatalf("failed test - unable to set timezone: %s", err)
+	}
+
+	wrkr := &worker{past: past, wake: make(chan bool, 1)}
+	wrkr.InitObjects()
+	wrkr.addObject(newScheduledObject("late", at(3*time.Hour)+" replicas=0"))
+	wrkr.addObject(newScheduledObject("early", at(time.Hour)+" replicas=0"))
+	wrkr.addObject(newScheduledObject("due", at(-time.Hour)+" replicas=0"))
+	wrkr.addObject(newScheduledObject("none"))
+
+	if len(wrkr.timeline) != 3 {
+		t.Errorf("failed test - expected 3 entries on timeline, got %d", len(wrkr.timeline))
+	}
+	select {
+	case <-wrkr.wake:
+	default:
+		t.Errorf("failed test - expected scale loop to be notified")
+	}
+
+	next, ok := wrkr.nextWakeup()
+	if !ok || next.After(now) {
+		t.Errorf("failed test - expected due object to be first, got %s", next)
+	}
+
+	dues := wrkr.popDue(now)
+	if len(dues) != 1 || dues[0].obj.UID != "due" || !dues[0].past.Equal(past) {
+		t.Errorf("failed test - expected object due to be due since %s, got %v", past, dues)
+	}
+	if e := wrkr.entries["due"]; e == nil || !e.past.Equal(now) || !e.at.After(now) {
+		t.Errorf("failed test - expected object due to be rescheduled, got %v", e)
+	}
+	if !wrkr.past.IsZero() {
+		t.Errorf("failed test - expected catch-up time to be reset after first run")
+	}
+
+	next, _ = wrkr.nextWakeup()
+	if next.Format("Mon 15:04") != at(time.Hour) {
+		t.Errorf("failed test - expected early object next, got %s", next)
+	}
+
+	wrkr.removeObject(&scanner.Object{UID: "early"})
+	if _, ok := wrkr.entries["early"]; ok {
+		t.Errorf("failed test - expected removed object to be removed from timeline")
+	}
+	next, _ = wrkr.nextWakeup()
+	if next.Format("Mon 15:04") != at(3*time.Hour) {
+		t.Errorf("failed test - expected late object next, got %s", next)
+	}
+
+	wrkr.addObject(newScheduledObject("late"))
+	if len(wrkr.timeline) != 1 {
+		t.Errorf("failed test - expected object without schedule to be removed, got %d entries", len(wrkr.timeline))
+	}
+}
+
+func TestTriggerTimeline(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-2 * time.Hour)
+	if err := schedule.SetTimeZone("UTC"); err != nil {
+		t.Fatalf("failed test - unable to set timezone: %s", err)
+	}
+	sc, _ := schedule.New(now.Add(-time.Hour).In(time.UTC).Format("Mon 15:04"))
+
+	wrkr := &worker{past: past, triggers: map[string]trigger.Trigger{}}
+	wrkr.InitObjects()
+	wrkr.AddTrigger("scheduled", &mockTrigger{cfg: trigger.Config{Schedule: []*schedule.Schedule{sc}}})
+	wrkr.AddTrigger("unscheduled", &mockTrigger{})
+	if len(wrkr.timeline) != 1 {
+		t.Errorf("failed test - expected 1 entry on timeline, got %d", len(wrkr.timeline))
+	}
+
+	dues := wrkr.popDue(now)
+	if len(dues) != 1 || dues[0].obj != nil || dues[0].trigger != "scheduled" {
+		t.Fatalf("failed test - expected scheduled trigger to be due, got %v", dues)
+	}
+	refs := getScheduledTriggerRefs(dues[0], now)
+	if len(refs) != 1 || refs[0].id != "scheduled" || refs[0].obj != nil || refs[0].sched != sc.Description {
+		t.Errorf("failed test - expected a single reference to the scheduled trigger, got %v", refs)
+	}
+
+	wrkr.triggers = map[string]trigger.Trigger{}
+	wrkr.rebuildTimeline()
+	if len(wrkr.timeline) != 0 {
+		t.Errorf("failed test - expected removed trigger to be removed from timeline")
+	}
+}
@@ -1,43 +1,239 @@
 package agent
 
 import (
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/golang/glog"
+
+	"github.com/joyrex2001/nightshift/internal/activity"
+	"github.com/joyrex2001/nightshift/internal/metrics"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+	"github.com/joyrex2001/nightshift/internal/trigger"
 )
 
-// StartTrigger will consume the triggerqueue channel and execute each
-// triggers sequentially. It will block until the channel is closed.
+// triggerJob is a trigger that is queued for execution, together with the
+// event that caused it, and the keys of the event objects in the inflight set
+// of the trigger queue.
+type triggerJob struct {
+	id    string
+	event trigger.Event
+	keys  []string
+}
+
+// triggerRef is a reference to a trigger by a scheduled event of an object,
+// or by the schedule of the trigger itself, in which case obj is nil. The
+// params are the parameters given with the trigger reference in the
+// schedule.
+type triggerRef struct {
+	id     string
+	params map[string]string
+	at     time.Time
+	sched  string
+	obj    *trigger.EventObject
+}
+
+// StartTrigger will start executing the queued triggers. It will block until
+// the trigger runner is stopped.
 func (a *worker) StartTrigger() {
-	for trgr := range a.trigqueue {
-		if err := a.triggers[trgr].Execute(); err != nil {
-			glog.Errorf("Error execute trigger: %s", err)
+	a.runner.start()
+	a.dispatch()
+	<-a.runner.done
+}
+
+// dispatch will start the queued trigger executions that are allowed to run.
+func (a *worker) dispatch() {
+	for _, job := range a.runner.next() {
+		go a.runJob(job)
+	}
+}
+
+// runJob will execute the given queued trigger execution, and dispatch the
+// next executions once it has finished.
+func (a *worker) runJob(job *triggerJob) {
+	if trgr, ok := a.getTrigger(job.id); ok {
+		a.executeTrigger(job.id, trgr, job.event)
+	} else {
+		glog.Errorf("Error execute trigger: trigger %s no longer available", job.id)
+	}
+	a.runner.finish(job)
+	a.dispatch()
+}
+
+// executeTrigger will execute given trigger with given event, retrying it as
+// configured, and record the result in the activity journal. If the trigger
+// failed, it will be added to the dead-letter list.
+func (a *worker) executeTrigger(id string, trgr trigger.Trigger, evt trigger.Event) {
+	start := time.Now()
+	attempts, err := trigger.ExecuteWithRetry(trgr, evt)
+	metrics.TriggerExecuted(id, err)
+	if err != nil {
+		glog.Errorf("Error execute trigger: %s", err)
+		a.addDeadLetter(DeadLetter{
+			TriggerId: id,
+			Event:     evt,
+			Time:      start,
+			Attempts:  attempts,
+			Error:     err.Error(),
+		})
+	}
+	activity.Add(newTriggerRecord(id, start, attempts, err))
+}
+
+// newTriggerRecord will return an activity record for the execution of the
+// trigger with given id.
+func newTriggerRecord(id string, start time.Time, attempts int, err error) activity.Record {
+	rec := activity.Record{
+		Time:      start,
+		Action:    "trigger",
+		TriggerId: id,
+		Attempts:  attempts,
+		Duration:  time.Since(start),
+		Error:     activity.ErrorString(err),
+	}
+	if serr, ok := err.(*trigger.StatusError); ok {
+		rec.Status = serr.StatusCode
+	}
+	return rec
+}
+
+// Wake will notify the triggers that implement trigger.Waker that the given
+// objects have been woken up manually, together with the objects in the same
+// namespaces that are still scaled down, and record the result in the
+// activity journal.
+func (a *worker) Wake(objs []trigger.EventObject) {
+	asleep := a.getAsleep(objs)
+	for id, trgr := range a.GetTriggers() {
+		wkr, ok := trgr.(trigger.Waker)
+		if !ok {
+			continue
+		}
+		start := time.Now()
+		err := wkr.Wake(objs, asleep)
+		if err != nil {
+			glog.Errorf("Error waking trigger %s: %s", id, err)
+		}
+		rec := newTriggerRecord(id, start, 1, err)
+		rec.Action = "wake"
+		activity.Add(rec)
+	}
+}
+
+// getAsleep will return the known objects in the namespaces of the given
+// objects that are scaled down, except for the given objects themselves.
+func (a *worker) getAsleep(objs []trigger.EventObject) []trigger.EventObject {
+	nss := map[string]bool{}
+	uids := map[string]bool{}
+	for _, obj := range objs {
+		nss[obj.Namespace] = true
+		uids[obj.UID] = true
+	}
+	res := []trigger.EventObject{}
+	for uid, obj := range a.GetObjects() {
+		if !nss[obj.Namespace] || uids[uid] || obj.Replicas > 0 {
+			continue
 		}
+		res = append(res, trigger.EventObject{
+			Namespace:   obj.Namespace,
+			Name:        obj.Name,
+			UID:         obj.UID,
+			ScannerId:   obj.ScannerId,
+			OldReplicas: obj.Replicas,
+			NewReplicas: obj.Replicas,
+		})
 	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Namespace != res[j].Namespace {
+			return res[i].Namespace < res[j].Namespace
+		}
+		return res[i].Name < res[j].Name
+	})
+	return res
 }
 
-// StopTrigger will stop the scaling loop.
+// StopTrigger will stop the trigger runner; executions that are running will
+// finish, but no new executions will be started.
 func (a *worker) StopTrigger() {
-	close(a.trigqueue)
+	a.runner.stop()
 }
 
 // queueTriggers will enqueue the collected triggers as specified in the
-// prodived list of trigger id's. Each trigger will be enqueued just once.
-func (a *worker) queueTriggers(trgrs []string) {
-	done := map[string]bool{}
-	for _, trgr := range trgrs {
-		if done[trgr] {
-			continue
+// provided list of trigger references. Each trigger will be enqueued just
+// once for each distinct set of parameters, with an event that contains all
+// objects that referred to it with these parameters.
+func (a *worker) queueTriggers(refs []triggerRef) {
+	order := []string{}
+	ids := map[string]string{}
+	events := map[string]*trigger.Event{}
+	for _, ref := range refs {
+		key := schedule.Invocation{Id: ref.id, Params: ref.params}.String()
+		evt, ok := events[key]
+		if !ok {
+			evt = &trigger.Event{
+				Time:     ref.at,
+				Schedule: ref.sched,
+				Params:   ref.params,
+				Objects:  []trigger.EventObject{},
+			}
+			events[key] = evt
+			ids[key] = ref.id
+			order = append(order, key)
 		}
-		_, ok := a.triggers[trgr]
-		if ok {
-			a.queueTrigger(trgr)
-		} else {
-			glog.Errorf("Error execute trigger: invalid trigger %s", trgr)
+		if ref.obj != nil {
+			if evt.ScannerId == "" {
+				evt.ScannerId = ref.obj.ScannerId
+			}
+			evt.Objects = append(evt.Objects, *ref.obj)
 		}
-		done[trgr] = true
 	}
+	for _, key := range order {
+		a.queueTrigger(ids[key], *events[key])
+	}
+}
+
+// queueTrigger will queue an execution of the trigger with given id, without
+// blocking. If the queue of the trigger is full, the execution is handled
+// according to the overflow setting of the trigger; rejected executions are
+// added to the dead-letter list.
+func (a *worker) queueTrigger(id string, evt trigger.Event) {
+	trgr, ok := a.getTrigger(id)
+	if !ok {
+		glog.Errorf("Error execute trigger: invalid trigger %s", id)
+		return
+	}
+	policy, err := trigger.NewQueuePolicy(trgr.GetConfig().Settings)
+	if err != nil {
+		glog.Errorf("Error queue trigger %s, using defaults: %s", id, err)
+		policy, _ = trigger.NewQueuePolicy(nil)
+	}
+	outcome := a.runner.enqueue(id, evt, *policy)
+	metrics.TriggerQueued(id, outcome)
+	switch outcome {
+	case outcomeDropped:
+		glog.Warningf("Dropped execution of trigger %s: queue is full", id)
+	case outcomeRejected:
+		glog.Warningf("Rejected execution of trigger %s: queue is full", id)
+		a.rejectTrigger(id, evt)
+	}
+	a.dispatch()
 }
 
-// queueTrigger will add a trigger to the triggerqueue.
-func (a *worker) queueTrigger(trgr string) {
-	a.trigqueue <- trgr
+// rejectTrigger will record the given execution of the trigger with given id
+// as failed, and add it to the dead-letter list, so it can be retried later.
+func (a *worker) rejectTrigger(id string, evt trigger.Event) {
+	now := time.Now()
+	err := fmt.Errorf("trigger %s rejected: queue is full", id)
+	a.addDeadLetter(DeadLetter{
+		TriggerId: id,
+		Event:     evt,
+		Time:      now,
+		Error:     err.Error(),
+	})
+	activity.Add(activity.Record{
+		Time:      now,
+		Action:    "trigger",
+		TriggerId: id,
+		Error:     activity.ErrorString(err),
+	})
 }
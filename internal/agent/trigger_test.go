@@ -1,20 +1,23 @@
 package agent
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/joyrex2001/nightshift/internal/activity"
+	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/trigger"
 )
 
 func TestHandleTriggers(t *testing.T) {
 	agent := &worker{}
 	agent.triggers = map[string]trigger.Trigger{}
-	agent.trigqueue = make(chan string)
+	agent.runner = newRunner()
 
-	mock1 := &mockTrigger{}
-	mock2 := &mockTrigger{}
+	mock1 := &mockTrigger{done: make(chan trigger.Event, 4)}
+	mock2 := &mockTrigger{done: make(chan trigger.Event, 1)}
 	mock3 := &mockTrigger{}
 	trigger.RegisterModule("trigger1", getTriggerFactory("trigger1", mock1))
 	trigger.RegisterModule("trigger2", getTriggerFactory("trigger2", mock2))
@@ -24,55 +27,198 @@ func TestHandleTriggers(t *testing.T) {
 	agent.AddTrigger("trigger3", mock3)
 
 	trgrs := []string{"trigger1", "trigger1", "trigger2", "trigger1", "trigger1"}
-	stopped := false
+	stopped := make(chan bool)
 	go func() {
 		agent.StartTrigger()
-		stopped = true
+		close(stopped)
 	}()
 
-	for _, trgr := range trgrs {
-		agent.queueTrigger(trgr)
+	for i, trgr := range trgrs {
+		agent.queueTrigger(trgr, trigger.Event{Time: time.Unix(int64(i), 0)})
 	}
 
-	time.Sleep(time.Second)
-
-	if mock1.exc != 4 {
-		t.Errorf("invalid number of calls to trigger 1; expected 1, got %d", mock1.exc)
-	}
-	if mock2.exc != 1 {
-		t.Errorf("invalid number of calls to trigger 2; expected 1, got %d", mock1.exc)
+	for _, tst := range []struct {
+		mock *mockTrigger
+		exc  int
+	}{{mock1, 4}, {mock2, 1}} {
+		for i := 0; i < tst.exc; i++ {
+			select {
+			case <-tst.mock.done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("invalid number of calls to trigger; expected %d, got %d", tst.exc, i)
+			}
+		}
+		if n := tst.mock.executions(); n != tst.exc {
+			t.Errorf("invalid number of calls to trigger; expected %d, got %d", tst.exc, n)
+		}
 	}
-	if mock3.exc != 0 {
-		t.Errorf("invalid number of calls to trigger 3; expected 0, got %d", mock1.exc)
+	if n := mock3.executions(); n != 0 {
+		t.Errorf("invalid number of calls to trigger 3; expected 0, got %d", n)
 	}
 	agent.StopTrigger()
-	time.Sleep(time.Second)
 
-	if !stopped {
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
 		t.Errorf("StopTrigger did not stop the trigger")
 	}
 }
 
 func TestQueueTriggers(t *testing.T) {
 	agent := &worker{}
-	agent.trigqueue = make(chan string)
+	agent.runner = newRunner()
 	agent.triggers = map[string]trigger.Trigger{
 		"trigger1": &mockTrigger{},
 		"trigger2": &mockTrigger{},
 	}
-	res := []string{}
-	trgrs := []string{"trigger1", "trigger1", "trigger2", "trigger3", "trigger1", "trigger1"}
-	go agent.queueTriggers(trgrs)
-	go func() {
-		for trgr := range agent.trigqueue {
-			res = append(res, trgr)
+	at := time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)
+	obj := func(name string) trigger.EventObject {
+		return trigger.EventObject{Name: name, ScannerId: "scanner-" + name, OldReplicas: 1}
+	}
+	ref := func(id, name string) triggerRef {
+		o := obj(name)
+		return triggerRef{id: id, at: at, sched: "mon 18:00", obj: &o}
+	}
+	refs := []triggerRef{
+		ref("trigger1", "a"),
+		ref("trigger1", "b"),
+		ref("trigger2", "b"),
+		ref("trigger3", "b"),
+		ref("trigger1", "c"),
+		{id: "trigger2", at: at, sched: "mon 18:00"},
+	}
+	agent.queueTriggers(refs)
+
+	evts := map[string]trigger.Event{}
+	for id, q := range agent.runner.queues {
+		if len(q.pending) != 1 {
+			t.Errorf("failed queueTriggers - expected 1 queued execution for %s, got %d", id, len(q.pending))
+			continue
 		}
-	}()
-	time.Sleep(time.Second)
-	close(agent.trigqueue)
+		evts[id] = q.pending[0].event
+	}
+	if len(evts) != 2 {
+		t.Errorf("failed queueTriggers - expected trigger1 and trigger2 to be queued, got %v", evts)
+	}
+	exp1 := trigger.Event{
+		Time:      at,
+		ScannerId: "scanner-a",
+		Schedule:  "mon 18:00",
+		Objects:   []trigger.EventObject{obj("a"), obj("b"), obj("c")},
+	}
+	if !reflect.DeepEqual(evts["trigger1"], exp1) {
+		t.Errorf("failed queueTriggers - expected event %v, got %v", exp1, evts["trigger1"])
+	}
+	if objs := evts["trigger2"].Objects; len(objs) != 1 || objs[0].Name != "b" {
+		t.Errorf("failed queueTriggers - expected event with object b, got %v", objs)
+	}
+}
+
+func TestExecuteTrigger(t *testing.T) {
+	agent := &worker{}
+	agent.executeTrigger("activity-ok", &mockTrigger{}, trigger.Event{})
+	agent.executeTrigger("activity-fail", &mockTrigger{err: errors.New("failed")}, trigger.Event{})
 
-	exp := []string{"trigger1", "trigger2"}
-	if !reflect.DeepEqual(res, exp) {
-		t.Errorf("failed queueTriggers - expected %s, got %s", exp, res)
+	if res := activity.Get(activity.Filter{Trigger: "activity-ok", Outcome: activity.OutcomeSuccess}); len(res) != 1 {
+		t.Errorf("failed executeTrigger - expected a successful activity record, got %v", res)
+	}
+	if res := activity.Get(activity.Filter{Trigger: "activity-fail", Outcome: activity.OutcomeFailure}); len(res) != 1 {
+		t.Errorf("failed executeTrigger - expected a failed activity record, got %v", res)
+	}
+}
+
+func TestQueueTriggersParams(t *testing.T) {
+	agent := &worker{}
+	agent.runner = newRunner()
+	agent.triggers = map[string]trigger.Trigger{"refreshdb": &mockTrigger{}}
+	at := time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)
+	ref := func(name string, params map[string]string) triggerRef {
+		return triggerRef{id: "refreshdb", params: params, at: at, obj: &trigger.EventObject{UID: name, Name: name}}
+	}
+	refs := []triggerRef{
+		ref("a", map[string]string{"db": "orders", "size": "small"}),
+		ref("b", map[string]string{"size": "small", "db": "orders"}),
+		ref("c", map[string]string{"db": "customers"}),
+		ref("d", nil),
+		ref("a", map[string]string{"db": "customers"}),
+	}
+	agent.queueTriggers(refs)
+
+	pending := agent.runner.queues["refreshdb"].pending
+	if len(pending) != 3 {
+		t.Fatalf("failed queueTriggers - expected 3 queued executions, got %d", len(pending))
+	}
+	exp := []struct {
+		params map[string]string
+		objs   []string
+	}{
+		{params: map[string]string{"db": "orders", "size": "small"}, objs: []string{"a", "b"}},
+		{params: map[string]string{"db": "customers"}, objs: []string{"c", "a"}},
+		{params: nil, objs: []string{"d"}},
+	}
+	for i, e := range exp {
+		evt := pending[i].event
+		objs := []string{}
+		for _, obj := range evt.Objects {
+			objs = append(objs, obj.Name)
+		}
+		if !reflect.DeepEqual(evt.Params, e.params) || !reflect.DeepEqual(objs, e.objs) {
+			t.Errorf("failed test %d - expected %v with %v, got %v with %v", i, e.params, e.objs, evt.Params, objs)
+		}
+	}
+
+	// the same invocation is coalesced with the queued execution
+	agent.queueTriggers(refs[1:2])
+	if len(agent.runner.queues["refreshdb"].pending) != 3 {
+		t.Errorf("failed queueTriggers - expected invocation to be coalesced")
+	}
+}
+
+type mockWaker struct {
+	mockTrigger
+	woken  []trigger.EventObject
+	asleep []trigger.EventObject
+}
+
+func (m *mockWaker) Wake(woken, asleep []trigger.EventObject) error {
+	m.woken = append(m.woken, woken...)
+	m.asleep = append(m.asleep, asleep...)
+	return m.err
+}
+
+func TestWake(t *testing.T) {
+	ok := &mockWaker{}
+	fail := &mockWaker{mockTrigger: mockTrigger{err: errors.New("failed")}}
+	agent := &worker{triggers: map[string]trigger.Trigger{
+		"wake-ok":   ok,
+		"wake-fail": fail,
+		"wake-none": &mockTrigger{},
+	}}
+	agent.InitObjects()
+	for _, obj := range []*scanner.Object{
+		{Namespace: "development", Name: "app", UID: "app", Replicas: 0},
+		{Namespace: "development", Name: "db", UID: "db", Replicas: 0},
+		{Namespace: "development", Name: "web", UID: "web", Replicas: 2},
+		{Namespace: "test", Name: "db", UID: "test-db", Replicas: 0},
+	} {
+		agent.addObject(obj)
+	}
+	objs := []trigger.EventObject{{Namespace: "development", Name: "app", UID: "app", NewReplicas: 1}}
+	agent.Wake(objs)
+
+	if !reflect.DeepEqual(ok.woken, objs) || !reflect.DeepEqual(fail.woken, objs) {
+		t.Errorf("failed wake - expected triggers to be woken with %v, got %v and %v", objs, ok.woken, fail.woken)
+	}
+	if len(ok.asleep) != 1 || ok.asleep[0].UID != "db" {
+		t.Errorf("failed wake - expected db to be asleep in the namespace, got %v", ok.asleep)
+	}
+	if res := activity.Get(activity.Filter{Trigger: "wake-ok", Outcome: activity.OutcomeSuccess}); len(res) != 1 || res[0].Action != "wake" {
+		t.Errorf("failed wake - expected a successful activity record, got %v", res)
+	}
+	if res := activity.Get(activity.Filter{Trigger: "wake-fail", Outcome: activity.OutcomeFailure}); len(res) != 1 {
+		t.Errorf("failed wake - expected a failed activity record, got %v", res)
+	}
+	if res := activity.Get(activity.Filter{Trigger: "wake-none"}); len(res) != 0 {
+		t.Errorf("failed wake - expected no activity record, got %v", res)
 	}
 }
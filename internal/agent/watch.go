@@ -16,19 +16,31 @@ type watch struct {
 	_quit chan bool // channel that will signal the scanner to stop watching
 }
 
-// StartWatch will start watching all configured scanners.
+// StartWatch will start watching all configured scanners. It will block
+// until the watchers are stopped by calling StopWatch().
 func (a *worker) StartWatch() {
+	a.watch(a.initWatchers())
+}
+
+// watch will run the given watchers, and resync the scanners periodically,
+// until the watchers are stopped.
+func (a *worker) watch(watchers []watch) {
 	quit := make(chan bool)
 	go a.resyncScanner(quit)
-	a.initWatchers()
-	a.runWatchers()
+	a.runWatchers(watchers)
 	quit <- true
 }
 
-// StopWatch will stop watching all configured scanners.
+// StopWatch will stop watching all configured scanners. The watchers are
+// signalled by closing their quit channel, so stopping watchers that have
+// already stopped will not block.
 func (a *worker) StopWatch() {
-	for _, wtc := range a.watchers {
-		wtc.quit <- true
+	a.m.Lock()
+	watchers := a.watchers
+	a.watchers = nil
+	a.m.Unlock()
+	for _, wtc := range watchers {
+		close(wtc.quit)
 	}
 }
 
@@ -36,9 +48,11 @@ func (a *worker) StopWatch() {
 // objects. This method is called periodically by the resyncScanner method to
 // make sure the known state reflects the actual state of the platform, and
 // makes the agent resilient against missed watch events due to e.g. network
-// connectivity problems.
+// connectivity problems. The scanned objects replace the known objects at
+// once, so the scale loop never sees an empty (partially scanned) set, and
+// the timeline is updated accordingly.
 func (a *worker) UpdateSchedule() {
-	a.InitObjects()
+	objects := map[string]*objectspq{}
 	for _, scnr := range a.GetScanners() {
 		objs, err := scnr.GetObjects()
 		if err != nil {
@@ -47,30 +61,39 @@ func (a *worker) UpdateSchedule() {
 		}
 		glog.V(5).Infof("Scan result: %#v", objs)
 		for _, obj := range objs {
-			a.addObject(obj)
+			pushObject(objects, obj)
 		}
 	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.objects = objects
+	a.rebuildTimeline()
 }
 
-// initWatchers will initialize the watchers for all available channels.
-func (a *worker) initWatchers() {
-	a.watchers = []watch{}
+// initWatchers will initialize the watchers for all available channels, and
+// return them.
+func (a *worker) initWatchers() []watch {
+	watchers := []watch{}
 	for _, scnr := range a.GetScanners() {
 		_quit := make(chan bool)
 		wtc, err := scnr.Watch(_quit)
 		if err != nil {
 			glog.Errorf("Error initialising watcher for scanner: %v", scnr.GetConfig())
 		} else {
-			a.watchers = append(a.watchers, watch{wtc, make(chan bool), _quit})
+			watchers = append(watchers, watch{wtc, make(chan bool), _quit})
 		}
 	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.watchers = watchers
+	return watchers
 }
 
-// runWatchers will run the watchers, and will block until the watchers are
-// stopped by calling StopWatch().
-func (a *worker) runWatchers() {
+// runWatchers will run the given watchers, and will block until the watchers
+// are stopped by calling StopWatch().
+func (a *worker) runWatchers(watchers []watch) {
 	var wg sync.WaitGroup
-	for _, _wtc := range a.watchers {
+	for _, _wtc := range watchers {
 		wtc := _wtc
 		wg.Add(1)
 		go func() {
@@ -83,13 +106,12 @@ func (a *worker) runWatchers() {
 
 // watchScanner will read the watch channel as provided by the scanners Watch
 // method, and will update the objects according to the events received on the
-// channel. This method blocks until the it receives a quit message on the
-// quit channel.
+// channel. This method blocks until the quit channel is closed.
 func (a *worker) watchScanner(wtc watch) {
 	for {
 		select {
 		case <-wtc.quit:
-			wtc._quit <- true
+			close(wtc._quit)
 			return
 		case event := <-wtc.event:
 			glog.V(4).Infof("Watch event: %v", event)
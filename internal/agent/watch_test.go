@@ -9,13 +9,14 @@ import (
 
 func TestStartStopWatch(t *testing.T) {
 	wrkr := &worker{}
-	scnr := &mockScanner{}
+	scnr := &mockScanner{stop: make(chan bool)}
 	wrkr.AddScanner(scnr)
 	go wrkr.StartWatch()
 	time.Sleep(time.Second)
 	wrkr.StopWatch()
-	time.Sleep(time.Second)
-	if !scnr.stop {
+	select {
+	case <-scnr.stop:
+	case <-time.After(5 * time.Second):
 		t.Error("scanner did not stop...")
 	}
 }
@@ -117,7 +118,8 @@ func TestWatchScanner(t *testing.T) {
 		for _, evt := range tst.event {
 			wtc.event <- evt
 		}
-		wtc.quit <- true
+		close(wtc.quit)
+		<-wtc._quit
 
 		objs := wrkr.GetObjects()
 		for j, obj := range objs {
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
@@ -10,34 +14,112 @@ import (
 )
 
 // New will instantiate a config object for given config file. It will return
-// an error if the config file is invalid, or does not exist.
+// an error if the config file does not exist, or is not valid yaml. If parts
+// of the configuration are invalid, these parts are disabled, and the
+// configuration is returned together with an Errors error listing all
+// problems.
 func New(file string) (*Config, error) {
 	y, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
 	m, err := loadConfig(y)
-	if err != nil {
-		return nil, err
-	}
-	if err = m.processSchedule(); err != nil {
+	errs, ok := err.(Errors)
+	if err != nil && !ok {
 		return nil, err
 	}
+	m.setLines(y)
+	errs = append(errs, m.processSchedule()...)
+	errs = append(errs, m.processClusters()...)
+	errs = append(errs, m.processEvents()...)
 	m.processDefaults()
 	m.processTriggers()
+	errs = append(errs, m.processTriggerRefs()...)
+	if len(errs) > 0 {
+		return m, errs
+	}
 	return m, nil
 }
 
-// loadConfig will load the given []byte of yaml data to a Config object.
+// loadConfig will load the given []byte of yaml data to a Config object. It
+// will return an error if the data is not valid yaml. Keys that are not part
+// of the configuration are ignored, and returned as an Errors error, including
+// their line number, together with the Config object.
 func loadConfig(y []byte) (*Config, error) {
 	cfg := &Config{}
-	err := yaml.Unmarshal(y, cfg)
-	if err != nil {
+	if err := yaml.Unmarshal(y, cfg); err != nil {
 		return nil, err
 	}
+	if err := yaml.UnmarshalStrict(y, &Config{}); err != nil {
+		terr, ok := err.(*yaml.TypeError)
+		if !ok {
+			return nil, err
+		}
+		return cfg, Errors(terr.Errors)
+	}
 	return cfg, nil
 }
 
+// setLines will store the line numbers of the schedule strings in the given
+// yaml data in the sections they are part of.
+func (c *Config) setLines(y []byte) {
+	pos := &positions{}
+	yaml.Unmarshal(y, pos)
+	for i, scan := range c.Scanner {
+		if i >= len(pos.Scanner) {
+			break
+		}
+		if scan.Default != nil {
+			scan.Default.lines = pos.Scanner[i].Default.Schedule
+		}
+		for j, depl := range scan.Deployment {
+			if j < len(pos.Scanner[i].Deployment) {
+				depl.lines = pos.Scanner[i].Deployment[j].Schedule
+			}
+		}
+	}
+	for i, trgr := range c.Trigger {
+		if i < len(pos.Trigger) {
+			trgr.lines = pos.Trigger[i].Schedule
+		}
+	}
+}
+
+// UnmarshalYAML will determine the line number of the value that is being
+// decoded. yaml.v2 doesn't expose the position of the values, but does include
+// the line number in the errors of values that can't be decoded. The value is
+// therefore decoded into a list, which fails for the schedule strings, and the
+// line number is taken from that error.
+func (p *position) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	err := unmarshal(&[]interface{}{})
+	if terr, ok := err.(*yaml.TypeError); ok && len(terr.Errors) > 0 {
+		if m := lineRe.FindStringSubmatch(terr.Errors[0]); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			*p = position(n)
+		}
+	}
+	return nil
+}
+
+// lineRe matches the line number in the errors of yaml.v2.
+var lineRe = regexp.MustCompile(`^line (\d+):`)
+
+// lineOf will return the prefix to be used in error messages for the schedule
+// string with given index, e.g. "line 12: ", or an empty string if the line
+// number is unknown.
+func lineOf(lines []position, i int) string {
+	if i >= len(lines) || lines[i] == 0 {
+		return ""
+	}
+	return fmt.Sprintf("line %d: ", lines[i])
+}
+
+// Error will return all errors, one per line, as required by the error
+// interface.
+func (e Errors) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
 // processDefaults will set default values for the configuration.
 func (c *Config) processDefaults() {
 	for _, scan := range c.Scanner {
@@ -60,21 +142,185 @@ func (c *Config) processTriggers() {
 	}
 }
 
+// processClusters will validate the configured clusters, and the clusters
+// referred to by the scanners. It will return an error for each cluster that
+// has no name or is defined more than once, and for each scanner that refers
+// to an unknown cluster. These clusters and scanners are removed.
+func (c *Config) processClusters() Errors {
+	errs := Errors{}
+	names := map[string]bool{}
+	clusters := []*Cluster{}
+	for i, cl := range c.Clusters {
+		if cl.Name == "" {
+			errs = append(errs, fmt.Sprintf("cluster[%d]: cluster without name", i))
+			continue
+		}
+		if names[cl.Name] {
+			errs = append(errs, fmt.Sprintf("cluster[%d]: duplicate cluster: %s", i, cl.Name))
+			continue
+		}
+		names[cl.Name] = true
+		clusters = append(clusters, cl)
+	}
+	c.Clusters = clusters
+	scanners := []*Scanner{}
+	for i, scan := range c.Scanner {
+		if scan.Cluster != "" && !names[scan.Cluster] {
+			errs = append(errs, fmt.Sprintf("%s: unknown cluster: %s", scan.describe(i), scan.Cluster))
+			continue
+		}
+		scanners = append(scanners, scan)
+	}
+	c.Scanner = scanners
+	return errs
+}
+
+// processEvents will validate the configured event sinks. It will return an
+// error for each sink that has no url, or has an invalid duration. These sinks
+// are removed.
+func (c *Config) processEvents() Errors {
+	errs := Errors{}
+	sinks := []*Events{}
+	for i, evt := range c.Events {
+		if evt.Url == "" {
+			errs = append(errs, fmt.Sprintf("events[%d]: events sink without url", i))
+			continue
+		}
+		valid := true
+		for _, d := range []string{evt.FlushInterval, evt.Backoff, evt.Timeout} {
+			if _, err := evt.parseDuration(d); err != nil {
+				errs = append(errs, fmt.Sprintf("events[%d]: invalid duration for events sink %s: %s", i, evt.Url, err))
+				valid = false
+			}
+		}
+		if valid {
+			sinks = append(sinks, evt)
+		}
+	}
+	c.Events = sinks
+	return errs
+}
+
+// GetFlushInterval will return the configured flush interval, or 0 if not
+// configured.
+func (e *Events) GetFlushInterval() time.Duration {
+	d, _ := e.parseDuration(e.FlushInterval)
+	return d
+}
+
+// GetBackoff will return the configured backoff, or 0 if not configured.
+func (e *Events) GetBackoff() time.Duration {
+	d, _ := e.parseDuration(e.Backoff)
+	return d
+}
+
+// GetTimeout will return the configured timeout, or 0 if not configured.
+func (e *Events) GetTimeout() time.Duration {
+	d, _ := e.parseDuration(e.Timeout)
+	return d
+}
+
+// GetRetries will return the configured number of retries, or 3 if not
+// configured.
+func (e *Events) GetRetries() int {
+	if e.Retries == nil {
+		return 3
+	}
+	return *e.Retries
+}
+
+// parseDuration will parse the given duration, which is 0 if empty.
+func (e *Events) parseDuration(d string) (time.Duration, error) {
+	if d == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(d)
+}
+
 // processSchedule will itterate through the config and process all schedule
-// strings and cache these. It will return an error if one or more schedules
-// are invalid.
-func (c *Config) processSchedule() error {
-	for _, scan := range c.Scanner {
+// strings and cache these. It will return an error for each invalid schedule,
+// together with its line number and the scanner or trigger it is part of.
+// Sections with an invalid schedule are disabled; their schedule is empty.
+func (c *Config) processSchedule() Errors {
+	errs := Errors{}
+	for i, scan := range c.Scanner {
 		if _, err := scan.Default.GetSchedule(); err != nil {
-			return err
+			errs = append(errs, scheduleErrors(scan.describe(i)+" default", scan.Default.Schedule, scan.Default.lines)...)
 		}
-		for _, depl := range scan.Deployment {
+		for j, depl := range scan.Deployment {
 			if _, err := depl.GetSchedule(); err != nil {
-				return err
+				errs = append(errs, scheduleErrors(fmt.Sprintf("%s deployment[%d]", scan.describe(i), j), depl.Schedule, depl.lines)...)
 			}
 		}
 	}
-	return nil
+	for i, trgr := range c.Trigger {
+		if _, err := trgr.GetSchedule(); err != nil {
+			errs = append(errs, scheduleErrors(fmt.Sprintf("trigger[%d] (id %s)", i, trgr.Id), trgr.Schedule, trgr.lines)...)
+		}
+	}
+	return errs
+}
+
+// scheduleErrors will return an error for each invalid schedule string in the
+// given list, prefixed with its line number and the given description.
+func scheduleErrors(where string, raw []string, lines []position) Errors {
+	errs := Errors{}
+	for i, text := range raw {
+		if text == "" {
+			continue
+		}
+		if _, err := schedule.New(text); err != nil {
+			errs = append(errs, fmt.Sprintf("%s%s: invalid schedule '%s': %s", lineOf(lines, i), where, text, err))
+		}
+	}
+	return errs
+}
+
+// processTriggerRefs will validate that the triggers and hooks the schedules
+// of the scanners refer to are defined in the trigger section. It will return
+// an error for each unknown reference, together with its line number and the
+// scanner it is part of. Sections that refer to an unknown trigger are
+// disabled, as scaling without the hooks that should run before or after
+// might do more harm than not scaling at all.
+func (c *Config) processTriggerRefs() Errors {
+	known := map[string]bool{}
+	for _, trgr := range c.Trigger {
+		known[strings.ToLower(trgr.Id)] = true
+	}
+	errs := Errors{}
+	check := func(where string, raw []string, lines []position) bool {
+		valid := true
+		for i, text := range raw {
+			sched, err := schedule.New(text)
+			if text == "" || err != nil {
+				continue
+			}
+			for _, inv := range sched.GetInvocations() {
+				if !known[inv.Id] {
+					errs = append(errs, fmt.Sprintf("%s%s: unknown trigger %s in schedule '%s'", lineOf(lines, i), where, inv.Id, sched.Description))
+					valid = false
+				}
+			}
+		}
+		return valid
+	}
+	for i, scan := range c.Scanner {
+		if scan.Default != nil && !check(scan.describe(i)+" default", scan.Default.Schedule, scan.Default.lines) {
+			scan.Default.schedule = nil
+		}
+		for j, depl := range scan.Deployment {
+			if !check(fmt.Sprintf("%s deployment[%d]", scan.describe(i), j), depl.Schedule, depl.lines) {
+				depl.schedule = nil
+			}
+		}
+	}
+	return errs
+}
+
+// describe will return a description of the scanner with given index, to be
+// used in error messages.
+func (s *Scanner) describe(i int) string {
+	return fmt.Sprintf("scanner[%d] (namespace %s)", i, strings.Join(s.Namespace, ","))
 }
 
 // GetSchedule will parse the schedule strings and return an array of schedule
@@ -104,19 +350,36 @@ func (d *Deployment) GetSchedule() ([]*schedule.Schedule, error) {
 	return d.schedule, err
 }
 
-// parseSchedule will parse the schedule strings and return an array of schedule
+// GetSchedule will parse the schedule strings and return an array of schedule
 // objects, or an error if the schedule strings are invalid.
+func (t *Trigger) GetSchedule() ([]*schedule.Schedule, error) {
+	var err error
+	if t.parsed {
+		return t.schedule, nil
+	}
+	t.parsed = true
+	t.schedule, err = parseSchedule(t.Schedule)
+	return t.schedule, err
+}
+
+// parseSchedule will parse the schedule strings and return an array of schedule
+// objects, or an error listing all schedule strings that are invalid.
 func parseSchedule(raw []string) ([]*schedule.Schedule, error) {
 	obj := []*schedule.Schedule{}
+	errs := Errors{}
 	for _, sched := range raw {
 		if sched == "" {
 			continue
 		}
 		s, err := schedule.New(sched)
 		if err != nil {
-			return nil, err
+			errs = append(errs, fmt.Sprintf("invalid schedule '%s': %s", sched, err))
+			continue
 		}
 		obj = append(obj, s)
 	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
 	return obj, nil
 }
@@ -3,7 +3,9 @@ package config
 import (
 	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kr/pretty"
 )
@@ -45,6 +47,54 @@ func TestNew(t *testing.T) {
 			file: "testdata/triggers.yaml",
 			err:  false,
 		},
+		{
+			file: "testdata/clusters.yaml",
+			err:  false,
+		},
+		{
+			file: "testdata/unknowncluster.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/duplicatecluster.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/triggerschedule.yaml",
+			err:  false,
+		},
+		{
+			file: "testdata/invalidtriggerschedule.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/events.yaml",
+			err:  false,
+		},
+		{
+			file: "testdata/invalidevents.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/unknownkey.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/unknowntrigger.yaml",
+			err:  true,
+		},
+		{
+			file: "testdata/invalidschedules.yaml",
+			err:  true,
+		},
+		{
+			file: "../../examples/config.yaml",
+			err:  false,
+		},
+		{
+			file: "../../examples/triggers.yaml",
+			err:  false,
+		},
 	}
 	for i, tst := range tests {
 		_, err := New(tst.file)
@@ -125,6 +175,27 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseClusters(t *testing.T) {
+	y, err := ioutil.ReadFile("testdata/clusters.yaml")
+	if err != nil {
+		t.Fatalf("failed test - test configfile %s does not exist", err)
+	}
+	res, err := loadConfig(y)
+	if err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	clusters := []*Cluster{
+		{Name: "dev", Kubeconfig: "/etc/nightshift/dev.kubeconfig"},
+		{Name: "test", Context: "test-cluster"},
+	}
+	if !reflect.DeepEqual(res.Clusters, clusters) {
+		t.Errorf("failed test - expected: %# v, got %# v", pretty.Formatter(clusters), pretty.Formatter(res.Clusters))
+	}
+	if res.Scanner[0].Cluster != "dev" || res.Scanner[1].Cluster != "test" {
+		t.Errorf("failed test - invalid scanner clusters: %s, %s", res.Scanner[0].Cluster, res.Scanner[1].Cluster)
+	}
+}
+
 func TestParseTrigger(t *testing.T) {
 	tests := []struct {
 		file   string
@@ -149,6 +220,20 @@ func TestParseTrigger(t *testing.T) {
 			},
 			err: false,
 		},
+		{
+			file: "testdata/triggerschedule.yaml",
+			result: &Config{
+				Trigger: []*Trigger{
+					{
+						Id:       "nightly",
+						Type:     "webhook",
+						Config:   map[string]string{"url": "http://localhost:8080/pipelines/nightly"},
+						Schedule: []string{"Mon-Fri 2:00", "Sat 4:00"},
+					},
+				},
+			},
+			err: false,
+		},
 	}
 	for i, tst := range tests {
 		y, err := ioutil.ReadFile(tst.file)
@@ -223,3 +308,141 @@ func TestProcessDefaults(t *testing.T) {
 		}
 	}
 }
+
+func TestEvents(t *testing.T) {
+	cfg, err := New("testdata/events.yaml")
+	if err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	if len(cfg.Events) != 2 {
+		t.Fatalf("failed test - expected 2 event sinks, got %d", len(cfg.Events))
+	}
+	evt := cfg.Events[0]
+	if evt.Source != "nightshift/production" || evt.BatchSize != 20 || evt.Headers["Authorization"] != "Bearer token" {
+		t.Errorf("failed test - unexpected event sink %# v", pretty.Formatter(evt))
+	}
+	if evt.GetFlushInterval() != 10*time.Second || evt.GetRetries() != 0 || evt.GetBackoff() != 0 {
+		t.Errorf("failed test - unexpected durations %s, %d, %s", evt.GetFlushInterval(), evt.GetRetries(), evt.GetBackoff())
+	}
+	if evt := cfg.Events[1]; evt.GetRetries() != 3 || evt.GetTimeout() != 0 {
+		t.Errorf("failed test - expected default retries, got %d", evt.GetRetries())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		file   string
+		errors []string
+	}{
+		{
+			file: "testdata/unknownkey.yaml",
+			errors: []string{
+				"line 8: field schedules not found in type config.Default",
+				"line 11: field namespaces not found in type config.Scanner",
+			},
+		},
+		{
+			file: "testdata/unknowntrigger.yaml",
+			errors: []string{
+				"line 14: scanner[0] (namespace development,test) default: unknown trigger notfy in schedule 'mon-fri 18:00 replicas=0 trigger=notfy'",
+				"line 19: scanner[0] (namespace development,test) deployment[0]: unknown trigger backup in schedule 'mon-fri 18:00 replicas=0 before=backup after=notify'",
+			},
+		},
+		{
+			file: "testdata/invalidschedules.yaml",
+			errors: []string{
+				"line 11: scanner[1] (namespace batch) default: invalid schedule 'Mon-Fri x:00 replicas=1'",
+				"line 12: scanner[1] (namespace batch) default: invalid schedule 'Mon-Fri replicas=0'",
+			},
+		},
+		{
+			file: "testdata/invalidtriggerschedule.yaml",
+			errors: []string{
+				"line 7: trigger[0] (id nightly): invalid schedule",
+			},
+		},
+	}
+	for i, tst := range tests {
+		_, err := New(tst.file)
+		if err == nil {
+			t.Errorf("failed test %d - expected err, but got none", i)
+			continue
+		}
+		for _, e := range tst.errors {
+			if !strings.Contains(err.Error(), e) {
+				t.Errorf("failed test %d - expected err to contain %q, got %q", i, e, err)
+			}
+		}
+	}
+}
+
+func TestPartial(t *testing.T) {
+	tests := []struct {
+		file     string
+		scanners int
+		check    func(*Config) bool
+	}{
+		{
+			file:     "testdata/unknownkey.yaml",
+			scanners: 2,
+			check: func(c *Config) bool {
+				return c.Scanner[0].Default.Schedule == nil && len(c.Scanner[1].Namespace) == 0
+			},
+		},
+		{
+			file:     "testdata/invalidschedules.yaml",
+			scanners: 2,
+			check: func(c *Config) bool {
+				def0, _ := c.Scanner[0].Default.GetSchedule()
+				def1, _ := c.Scanner[1].Default.GetSchedule()
+				return len(def0) == 1 && len(def1) == 0
+			},
+		},
+		{
+			file:     "testdata/unknowntrigger.yaml",
+			scanners: 1,
+			check: func(c *Config) bool {
+				def, _ := c.Scanner[0].Default.GetSchedule()
+				depl, _ := c.Scanner[0].Deployment[0].GetSchedule()
+				return len(def) == 0 && len(depl) == 0 && len(c.Trigger) == 1
+			},
+		},
+		{
+			file:     "testdata/unknowncluster.yaml",
+			scanners: 0,
+			check: func(c *Config) bool {
+				return len(c.Clusters) == 1
+			},
+		},
+		{
+			file:     "testdata/duplicatecluster.yaml",
+			scanners: 0,
+			check: func(c *Config) bool {
+				return len(c.Clusters) == 1 && c.Clusters[0].Context == ""
+			},
+		},
+		{
+			file:     "testdata/invalidevents.yaml",
+			scanners: 0,
+			check: func(c *Config) bool {
+				return len(c.Events) == 0
+			},
+		},
+	}
+	for i, tst := range tests {
+		cfg, err := New(tst.file)
+		if err == nil {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if cfg == nil {
+			t.Errorf("failed test %d - expected valid parts of config, got nil", i)
+			continue
+		}
+		if len(cfg.Scanner) != tst.scanners || !tst.check(cfg) {
+			t.Errorf("failed test %d - unexpected config %# v", i, pretty.Formatter(cfg))
+		}
+	}
+	if cfg, _ := New("testdata/invalidyaml.yaml"); cfg != nil {
+		t.Errorf("failed test - expected no config for invalid yaml, got %# v", pretty.Formatter(cfg))
+	}
+}
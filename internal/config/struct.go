@@ -4,10 +4,52 @@ import (
 	"github.com/joyrex2001/nightshift/internal/schedule"
 )
 
-// Config is reflection of the yaml root configuration entrypoint.
+// Config is reflection of the yaml root configuration entrypoint. The
+// sections that are read by viper (web, generic, etc.) are part of the same
+// file, and are included so they are allowed when the file is decoded
+// strictly.
 type Config struct {
-	Trigger []*Trigger `yaml:"trigger"`
-	Scanner []*Scanner `yaml:"scanner"`
+	Clusters  []*Cluster             `yaml:"clusters"`
+	Trigger   []*Trigger             `yaml:"trigger"`
+	Scanner   []*Scanner             `yaml:"scanner"`
+	Events    []*Events              `yaml:"events"`
+	Web       map[string]interface{} `yaml:"web"`
+	Generic   map[string]interface{} `yaml:"generic"`
+	Logging   map[string]interface{} `yaml:"logging"`
+	Activity  map[string]interface{} `yaml:"activity"`
+	OpenShift map[string]interface{} `yaml:"openshift"`
+}
+
+// Errors is the error that is returned when the configuration contains one
+// or more invalid parts, such as unknown keys, invalid schedules, or
+// schedules that refer to unknown triggers.
+type Errors []string
+
+// position is the line number of a value in the configuration file, or 0 if
+// unknown.
+type position int
+
+// positions mirrors the sections of the configuration that contain
+// schedules, and is used to find the line numbers of the schedule strings.
+type positions struct {
+	Scanner []struct {
+		Default struct {
+			Schedule []position `yaml:"schedule"`
+		} `yaml:"default"`
+		Deployment []struct {
+			Schedule []position `yaml:"schedule"`
+		} `yaml:"deployment"`
+	} `yaml:"scanner"`
+	Trigger []struct {
+		Schedule []position `yaml:"schedule"`
+	} `yaml:"trigger"`
+}
+
+// Cluster is reflection of the yaml configuration file's section "clusters".
+type Cluster struct {
+	Name       string `yaml:"name"`
+	Kubeconfig string `yaml:"kubeconfig"`
+	Context    string `yaml:"context"`
 }
 
 // Scanner is reflection of the yaml configuration file's section "scanner".
@@ -16,13 +58,31 @@ type Scanner struct {
 	Default    *Default      `yaml:"default"`
 	Deployment []*Deployment `yaml:"deployment"`
 	Type       string        `yaml:"type"`
+	Cluster    string        `yaml:"cluster"`
 }
 
 // Trigger is reflection of the yaml configuration file's section "trigger".
 type Trigger struct {
-	Id     string            `yaml:"id"`
-	Type   string            `yaml:"type"`
-	Config map[string]string `yaml:"config"`
+	Id       string            `yaml:"id"`
+	Type     string            `yaml:"type"`
+	Config   map[string]string `yaml:"config"`
+	Schedule []string          `yaml:"schedule"`
+	schedule []*schedule.Schedule
+	parsed   bool
+	lines    []position
+}
+
+// Events is reflection of the yaml configuration file's section "events".
+type Events struct {
+	Url           string            `yaml:"url"`
+	Source        string            `yaml:"source"`
+	Headers       map[string]string `yaml:"headers"`
+	BatchSize     int               `yaml:"batchSize"`
+	FlushInterval string            `yaml:"flushInterval"`
+	QueueSize     int               `yaml:"queueSize"`
+	Retries       *int              `yaml:"retries"`
+	Backoff       string            `yaml:"backoff"`
+	Timeout       string            `yaml:"timeout"`
 }
 
 // Default is reflection of the yaml configuration file's section "default".
@@ -31,6 +91,7 @@ type Default struct {
 	Schedule []string `yaml:"schedule"`
 	schedule []*schedule.Schedule
 	parsed   bool
+	lines    []position
 }
 
 // Deployment is reflection of the yaml configuration file's section
@@ -41,4 +102,5 @@ type Deployment struct {
 	Schedule []string `yaml:"schedule"`
 	schedule []*schedule.Schedule
 	parsed   bool
+	lines    []position
 }
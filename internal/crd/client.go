@@ -0,0 +1,83 @@
+package crd
+
+import (
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// KubernetesGetter is the function that is used to connect to the cluster
+// with given name, or to the default cluster if no name is given.
+type KubernetesGetter func(cluster string) (*rest.Config, error)
+
+var kubernetesGetter KubernetesGetter = scanner.GetKubernetes
+
+// SetKubernetesGetter will set the function that is used to connect to
+// kubernetes.
+func SetKubernetesGetter(getter KubernetesGetter) {
+	kubernetesGetter = getter
+}
+
+// List will return the NightshiftSchedule resources in all namespaces of the
+// cluster with given name, or of the default cluster if no name is given.
+func List(cluster string) ([]*NightshiftSchedule, error) {
+	client, err := newClient(cluster)
+	if err != nil {
+		return nil, err
+	}
+	data, err := client.Get().Resource(Resource).DoRaw()
+	if err != nil {
+		return nil, err
+	}
+	res := &list{}
+	if err := json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+	for _, sched := range res.Items {
+		sched.Cluster = cluster
+	}
+	return res.Items, nil
+}
+
+// SetStatus will update the status of the given resource, if it differs from
+// the current status.
+func SetStatus(sched *NightshiftSchedule, status Status) error {
+	if sched.Status == status {
+		return nil
+	}
+	client, err := newClient(sched.Cluster)
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return err
+	}
+	err = client.Patch(types.MergePatchType).
+		Namespace(sched.Namespace).
+		Resource(Resource).
+		Name(sched.Name).
+		SubResource("status").
+		Body(patch).
+		Do().
+		Error()
+	if err != nil {
+		return err
+	}
+	sched.Status = status
+	return nil
+}
+
+// newClient will return a rest client for the NightshiftSchedule api group
+// on the cluster with given name.
+func newClient(cluster string) (*rest.RESTClient, error) {
+	kubernetes, err := kubernetesGetter(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return scanner.NewRESTClient(kubernetes, schema.GroupVersion{Group: Group, Version: Version}, "/apis")
+}
@@ -0,0 +1,103 @@
+package crd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+// fakeScheduleAPI is a minimal kubernetes api server that supports listing
+// NightshiftSchedule resources, and patching their status.
+type fakeScheduleAPI struct {
+	m       sync.Mutex
+	items   []*NightshiftSchedule
+	patches map[string]Status
+}
+
+func (f *fakeScheduleAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	base := "/apis/" + Group + "/" + Version
+	switch {
+	case r.Method == "GET" && r.URL.Path == base+"/"+Resource:
+		json.NewEncoder(w).Encode(&list{Items: f.items})
+	case r.Method == "PATCH":
+		for _, item := range f.items {
+			path := fmt.Sprintf("%s/namespaces/%s/%s/%s/status", base, item.Namespace, Resource, item.Name)
+			if r.URL.Path != path {
+				continue
+			}
+			if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+				w.WriteHeader(http.StatusUnsupportedMediaType)
+				return
+			}
+			patch := struct {
+				Status Status `json:"status"`
+			}{}
+			json.NewDecoder(r.Body).Decode(&patch)
+			f.patches[item.Namespace+"/"+item.Name] = patch.Status
+			json.NewEncoder(w).Encode(item)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestClient(t *testing.T) {
+	api := &fakeScheduleAPI{
+		items: []*NightshiftSchedule{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "dev", Name: "office-hours", Generation: 2},
+				Spec:       Spec{Schedule: []string{"Mon-Fri 9:00 replicas=1"}},
+				Status:     Status{Valid: true, ObservedGeneration: 1},
+			},
+		},
+		patches: map[string]Status{},
+	}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+	SetKubernetesGetter(func(cluster string) (*rest.Config, error) {
+		if cluster != "test" {
+			return nil, fmt.Errorf("unknown cluster: %s", cluster)
+		}
+		return &rest.Config{Host: srv.URL}, nil
+	})
+
+	if _, err := List("other"); err == nil {
+		t.Errorf("failed test - expected err for unknown cluster")
+	}
+	scheds, err := List("test")
+	if err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	if len(scheds) != 1 || scheds[0].Cluster != "test" || scheds[0].Spec.Schedule[0] != "Mon-Fri 9:00 replicas=1" {
+		t.Fatalf("failed test - unexpected resources %v", scheds)
+	}
+
+	// the status is only patched if it changed
+	status := Status{Valid: false, Message: "invalid", ObservedGeneration: 2}
+	if err := SetStatus(scheds[0], status); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if api.patches["dev/office-hours"] != status || scheds[0].Status != status {
+		t.Errorf("failed test - expected status %v, got %v", status, api.patches)
+	}
+	delete(api.patches, "dev/office-hours")
+	if err := SetStatus(scheds[0], status); err != nil || len(api.patches) != 0 {
+		t.Errorf("failed test - expected unchanged status not to be patched (%v)", err)
+	}
+
+	missing := &NightshiftSchedule{ObjectMeta: metav1.ObjectMeta{Namespace: "dev", Name: "missing"}, Cluster: "test"}
+	if err := SetStatus(missing, status); err == nil {
+		t.Errorf("failed test - expected err for missing resource")
+	}
+}
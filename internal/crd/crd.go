@@ -0,0 +1,156 @@
+package crd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+	"github.com/joyrex2001/nightshift/internal/schedule"
+)
+
+const (
+	// Group is the api group of the NightshiftSchedule custom resource.
+	Group string = "nightshift.joyrex2001.com"
+	// Version is the api version of the NightshiftSchedule custom resource.
+	Version string = "v1alpha1"
+	// Resource is the plural resource name of the NightshiftSchedule custom
+	// resource.
+	Resource string = "nightshiftschedules"
+)
+
+// NightshiftSchedule is a namespaced custom resource that defines the
+// schedule for the objects in its namespace that match the selector.
+type NightshiftSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              Spec   `json:"spec"`
+	Status            Status `json:"status,omitempty"`
+	// Cluster is the name of the cluster the resource was found in.
+	Cluster string `json:"-"`
+}
+
+// Spec is the specification of a NightshiftSchedule. The Triggers are added
+// to every schedule that doesn't refer to a trigger itself, and the schedules
+// are defined in Timezone, or in the globally configured timezone if empty.
+type Spec struct {
+	Type     string   `json:"type,omitempty"`
+	Selector string   `json:"selector,omitempty"`
+	Schedule []string `json:"schedule"`
+	Triggers []string `json:"triggers,omitempty"`
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+// Status is the status of a NightshiftSchedule, as written by nightshift. It
+// reflects if the spec of the given generation is valid, and if not, why.
+type Status struct {
+	Valid              bool   `json:"valid"`
+	Message            string `json:"message,omitempty"`
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+}
+
+// list is a list of NightshiftSchedule resources, as returned by the api.
+type list struct {
+	Items []*NightshiftSchedule `json:"items"`
+}
+
+// Key will return a textual representation of the resource, which changes
+// when the resource is added, removed or its spec is updated.
+func (s *NightshiftSchedule) Key() string {
+	return fmt.Sprintf("%s/%s/%s/%s/%d", s.Cluster, s.Namespace, s.Name, s.UID, s.Generation)
+}
+
+// GetScannerConfig will return the scanner configuration for this resource,
+// with given priority. It will return an error if the spec is invalid, or
+// refers to a trigger that is not in the given list of trigger ids.
+func (s *NightshiftSchedule) GetScannerConfig(triggers []string, prio int) (scanner.Config, error) {
+	typ := strings.ToLower(s.Spec.Type)
+	if typ == "" {
+		typ = "openshift"
+	}
+	if _, err := scanner.New(typ); err != nil {
+		return scanner.Config{}, err
+	}
+	if _, err := labels.Parse(s.Spec.Selector); err != nil {
+		return scanner.Config{}, fmt.Errorf("invalid selector %s: %s", s.Spec.Selector, err)
+	}
+	sched, err := s.GetSchedule(triggers)
+	if err != nil {
+		return scanner.Config{}, err
+	}
+	return scanner.Config{
+		Id:        s.Name,
+		Type:      typ,
+		Cluster:   s.Cluster,
+		Namespace: s.Namespace,
+		Label:     s.Spec.Selector,
+		Schedule:  sched,
+		Priority:  prio,
+	}, nil
+}
+
+// GetSchedule will parse the schedule strings of this resource, and return
+// an array of schedule objects. It will return an error if a schedule is
+// invalid, the timezone is unknown, or if a schedule refers to a trigger that
+// is not in the given list of trigger ids.
+func (s *NightshiftSchedule) GetSchedule(triggers []string) ([]*schedule.Schedule, error) {
+	var loc *time.Location
+	if s.Spec.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(s.Spec.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %s: %s", s.Spec.Timezone, err)
+		}
+	}
+	known := map[string]bool{}
+	for _, id := range triggers {
+		known[strings.ToLower(id)] = true
+	}
+	res := []*schedule.Schedule{}
+	for _, text := range s.Spec.Schedule {
+		if text == "" {
+			continue
+		}
+		sched, err := schedule.New(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %s", text, err)
+		}
+		if len(sched.GetTriggers()) == 0 && len(s.Spec.Triggers) > 0 {
+			text = text + " trigger=" + strings.Join(s.Spec.Triggers, ",")
+			if sched, err = schedule.New(text); err != nil {
+				return nil, fmt.Errorf("invalid triggers %s: %s", strings.Join(s.Spec.Triggers, ","), err)
+			}
+		}
+		for _, inv := range sched.GetInvocations() {
+			if !known[inv.Id] {
+				return nil, fmt.Errorf("unknown trigger %s in schedule '%s'", inv.Id, text)
+			}
+		}
+		sched.SetLocation(loc)
+		res = append(res, sched)
+	}
+	return res, nil
+}
+
+// Sort will sort the given resources in the order of priority, lowest
+// priority first. Resources without a selector apply to the whole namespace,
+// and have a lower priority than resources with a selector, similar to the
+// default and deployment sections of the configuration file.
+func Sort(scheds []*NightshiftSchedule) {
+	sort.SliceStable(scheds, func(i, j int) bool {
+		si, sj := scheds[i], scheds[j]
+		if (si.Spec.Selector == "") != (sj.Spec.Selector == "") {
+			return si.Spec.Selector == ""
+		}
+		if si.Cluster != sj.Cluster {
+			return si.Cluster < sj.Cluster
+		}
+		if si.Namespace != sj.Namespace {
+			return si.Namespace < sj.Namespace
+		}
+		return si.Name < sj.Name
+	})
+}
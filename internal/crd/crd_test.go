@@ -0,0 +1,151 @@
+package crd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+type mockScanner struct{ scanner.Scanner }
+
+func init() {
+	scanner.RegisterModule("mockscanner", func() (scanner.Scanner, error) { return &mockScanner{}, nil })
+}
+
+func newSchedule(ns, name string, spec Spec) *NightshiftSchedule {
+	return &NightshiftSchedule{
+		ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name},
+		Spec:       spec,
+	}
+}
+
+func TestGetScannerConfig(t *testing.T) {
+	triggers := []string{"notify", "RefreshDB"}
+	tests := []struct {
+		spec     Spec
+		typ      string
+		label    string
+		schedule []string
+		err      string
+	}{
+		{
+			spec:     Spec{Schedule: []string{"Mon-Fri 9:00 replicas=1", "", "Mon-Fri 18:00 replicas=0"}},
+			typ:      "openshift",
+			schedule: []string{"mon-fri 9:00 replicas=1", "mon-fri 18:00 replicas=0"},
+		},
+		{
+			spec: Spec{
+				Type:     "MockScanner",
+				Selector: "app in (web,api)",
+				Schedule: []string{"Mon-Fri 9:00 replicas=1", "Mon-Fri 18:00 replicas=0 trigger=notify"},
+				Triggers: []string{"refreshdb(db=orders)"},
+			},
+			typ:      "mockscanner",
+			label:    "app in (web,api)",
+			schedule: []string{"mon-fri 9:00 replicas=1 trigger=refreshdb(db=orders)", "mon-fri 18:00 replicas=0 trigger=notify"},
+		},
+		{
+			spec: Spec{Schedule: []string{"Mon-Fri 9:00 replicas=1 before=notify after=refreshdb"}},
+			typ:  "openshift",
+		},
+		{
+			spec: Spec{Type: "unknown"},
+			err:  "invalid scannertype",
+		},
+		{
+			spec: Spec{Selector: "app in (web"},
+			err:  "invalid selector",
+		},
+		{
+			spec: Spec{Schedule: []string{"Mon-Fri replicas=1"}},
+			err:  "invalid schedule",
+		},
+		{
+			spec: Spec{Schedule: []string{"Mon-Fri 9:00 replicas=1"}, Timezone: "Mars/Olympus"},
+			err:  "invalid timezone",
+		},
+		{
+			spec: Spec{Schedule: []string{"Mon-Fri 9:00 replicas=1 trigger=unknown"}},
+			err:  "unknown trigger unknown",
+		},
+		{
+			spec: Spec{Schedule: []string{"Mon-Fri 9:00 replicas=1 after=unknown"}},
+			err:  "unknown trigger unknown",
+		},
+		{
+			spec: Spec{Schedule: []string{"Mon-Fri 9:00 replicas=1"}, Triggers: []string{"unknown"}},
+			err:  "unknown trigger unknown",
+		},
+		{
+			spec: Spec{Schedule: []string{"Mon-Fri 9:00 replicas=1"}, Triggers: []string{"notify("}},
+			err:  "invalid triggers",
+		},
+	}
+	for i, tst := range tests {
+		sched := newSchedule("dev", "office-hours", tst.spec)
+		sched.Cluster = "test"
+		cfg, err := sched.GetScannerConfig(triggers, 42)
+		if err != nil && tst.err == "" {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+			continue
+		}
+		if tst.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tst.err) {
+				t.Errorf("failed test %d - expected err containing %q, got %v", i, tst.err, err)
+			}
+			continue
+		}
+		if cfg.Type != tst.typ || cfg.Label != tst.label || cfg.Id != "office-hours" ||
+			cfg.Namespace != "dev" || cfg.Cluster != "test" || cfg.Priority != 42 {
+			t.Errorf("failed test %d - unexpected config %v", i, cfg)
+		}
+		if tst.schedule == nil {
+			continue
+		}
+		sched2 := []string{}
+		for _, s := range cfg.Schedule {
+			sched2 = append(sched2, s.Description)
+		}
+		if strings.Join(sched2, ";") != strings.Join(tst.schedule, ";") {
+			t.Errorf("failed test %d - expected schedule %v, got %v", i, tst.schedule, sched2)
+		}
+	}
+}
+
+func TestGetScheduleTimezone(t *testing.T) {
+	sched := newSchedule("dev", "office-hours", Spec{
+		Schedule: []string{"Mon-Sun 9:00 replicas=1"},
+		Timezone: "America/New_York",
+	})
+	res, err := sched.GetSchedule(nil)
+	if err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	now := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+	next, _ := res[0].GetNextTrigger(now)
+	if exp := time.Date(2019, 1, 1, 14, 0, 0, 0, time.UTC); !next.Equal(exp) {
+		t.Errorf("failed test - expected %s, got %s", exp, next)
+	}
+}
+
+func TestSort(t *testing.T) {
+	scheds := []*NightshiftSchedule{
+		newSchedule("dev", "web", Spec{Selector: "app=web"}),
+		newSchedule("test", "default", Spec{}),
+		newSchedule("dev", "default", Spec{}),
+		newSchedule("dev", "api", Spec{Selector: "app=api"}),
+	}
+	Sort(scheds)
+	res := []string{}
+	for _, sched := range scheds {
+		res = append(res, sched.Namespace+"/"+sched.Name)
+	}
+	exp := "dev/default,test/default,dev/api,dev/web"
+	if strings.Join(res, ",") != exp {
+		t.Errorf("failed test - expected %s, got %s", exp, strings.Join(res, ","))
+	}
+}
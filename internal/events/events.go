@@ -0,0 +1,107 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/activity"
+)
+
+// typePrefix is the prefix of the type of the events; the type is the
+// prefix followed by the action, e.g. com.joyrex2001.nightshift.scale.
+const typePrefix = "com.joyrex2001.nightshift."
+
+// Event is a CloudEvents 1.0 event in the structured json format. The data
+// of the event is the activity record of the action that has been taken by
+// nightshift.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	Id              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            activity.Record `json:"data"`
+}
+
+var (
+	m        sync.Mutex
+	sinks    []*Sink
+	draining sync.WaitGroup
+)
+
+// NewEvent will return the event for given activity record, with given id
+// and source. The type of the event is based on the action of the record,
+// and is suffixed with .failed if the action failed. The subject is the
+// object the action applied to, as namespace/name, or the trigger id.
+func NewEvent(id, source string, rec activity.Record) Event {
+	typ := typePrefix + strings.ToLower(rec.Action)
+	if rec.Error != "" {
+		typ += ".failed"
+	}
+	subject := rec.TriggerId
+	if rec.Object != "" {
+		subject = rec.Namespace + "/" + rec.Object
+	}
+	return Event{
+		SpecVersion:     "1.0",
+		Id:              id,
+		Source:          source,
+		Type:            typ,
+		Subject:         subject,
+		Time:            rec.Time,
+		DataContentType: "application/json",
+		Data:            rec,
+	}
+}
+
+// SetSinks will start sinks for the given configurations, and replace the
+// current sinks. The current sinks are stopped in the background, after
+// delivering the events they have queued, so a slow or unreachable endpoint
+// does not delay the caller.
+func SetSinks(cfgs []Config) {
+	new := []*Sink{}
+	for _, cfg := range cfgs {
+		snk := NewSink(cfg)
+		snk.Start()
+		new = append(new, snk)
+	}
+	m.Lock()
+	old := sinks
+	sinks = new
+	m.Unlock()
+	for _, snk := range old {
+		draining.Add(1)
+		go func(snk *Sink) {
+			defer draining.Done()
+			snk.Stop()
+		}(snk)
+	}
+}
+
+// Publish will publish the given activity record as an event to all sinks.
+// It will not block; if the queue of a sink is full, the event is dropped
+// for that sink. The events are queued while holding the lock, so they are
+// never queued to sinks that have been stopped by SetSinks.
+func Publish(rec activity.Record) {
+	m.Lock()
+	defer m.Unlock()
+	if len(sinks) == 0 {
+		return
+	}
+	id := newId()
+	for _, snk := range sinks {
+		snk.Publish(NewEvent(id, snk.config.Source, rec))
+	}
+}
+
+// newId will return a random id for an event.
+func newId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
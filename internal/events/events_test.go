@@ -0,0 +1,130 @@
+package events
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/activity"
+)
+
+func TestNewEvent(t *testing.T) {
+	now := time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		rec     activity.Record
+		typ     string
+		subject string
+	}{
+		{
+			rec:     activity.Record{Action: "scale", Namespace: "dev", Object: "app"},
+			typ:     "com.joyrex2001.nightshift.scale",
+			subject: "dev/app",
+		},
+		{
+			rec:     activity.Record{Action: "restore", Namespace: "dev", Object: "app", Error: "failed"},
+			typ:     "com.joyrex2001.nightshift.restore.failed",
+			subject: "dev/app",
+		},
+		{
+			rec:     activity.Record{Action: "trigger", TriggerId: "notify"},
+			typ:     "com.joyrex2001.nightshift.trigger",
+			subject: "notify",
+		},
+		{
+			rec: activity.Record{Action: "Save"},
+			typ: "com.joyrex2001.nightshift.save",
+		},
+	}
+	for i, tst := range tests {
+		tst.rec.Time = now
+		evt := NewEvent("1234", "nightshift/test", tst.rec)
+		if evt.Type != tst.typ {
+			t.Errorf("failed test %d - expected type %s, got %s", i, tst.typ, evt.Type)
+		}
+		if evt.Subject != tst.subject {
+			t.Errorf("failed test %d - expected subject %s, got %s", i, tst.subject, evt.Subject)
+		}
+		if evt.SpecVersion != "1.0" || evt.Id != "1234" || evt.Source != "nightshift/test" || !evt.Time.Equal(now) {
+			t.Errorf("failed test %d - unexpected event %v", i, evt)
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+	recv := make(chan []Event, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		evts := []Event{}
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &evts)
+		recv <- evts
+	}))
+	defer srv.Close()
+
+	// without sinks, publishing is a no-op
+	Publish(activity.Record{Action: "scale"})
+
+	SetSinks([]Config{
+		{Url: srv.URL, Source: "a"},
+		{Url: srv.URL, Source: "b"},
+	})
+	Publish(activity.Record{Action: "scale", Namespace: "dev", Object: "app"})
+	SetSinks(nil)
+
+	ids := map[string]bool{}
+	sources := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		evts := <-recv
+		if len(evts) != 1 {
+			t.Errorf("failed test - expected 1 event, got %v", evts)
+			continue
+		}
+		ids[evts[0].Id] = true
+		sources[evts[0].Source] = true
+	}
+	if len(ids) != 1 || len(sources) != 2 {
+		t.Errorf("failed test - expected same id for both sinks, got ids %v, sources %v", ids, sources)
+	}
+}
+
+func TestSetSinksAsync(t *testing.T) {
+	release := make(chan bool)
+	recv := make(chan []Event, 10)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		evts := []Event{}
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &evts)
+		recv <- evts
+	}))
+	defer slow.Close()
+
+	SetSinks([]Config{{Url: slow.URL, Source: "slow"}})
+	Publish(activity.Record{Action: "scale", Namespace: "dev", Object: "app"})
+
+	// replacing the sinks will not wait for the slow endpoint
+	done := make(chan bool)
+	go func() {
+		SetSinks(nil)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("failed test - SetSinks blocked on a slow sink")
+	}
+
+	// the old sink still delivers the events that were queued
+	close(release)
+	select {
+	case evts := <-recv:
+		if len(evts) != 1 || evts[0].Source != "slow" {
+			t.Errorf("failed test - expected the queued event, got %v", evts)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("failed test - queued event was not delivered")
+	}
+	draining.Wait()
+}
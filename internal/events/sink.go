@@ -0,0 +1,225 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/joyrex2001/nightshift/internal/metrics"
+)
+
+const (
+	outcomeDelivered = "delivered"
+	outcomeFailed    = "failed"
+	outcomeDropped   = "dropped"
+)
+
+// Config is the configuration of a sink. Events are posted to Url in batches
+// of at most BatchSize events, at least every FlushInterval. At most
+// QueueSize events are kept while waiting to be delivered. Failed deliveries
+// are retried Retries times, with a delay that starts at Backoff and doubles
+// after each attempt.
+type Config struct {
+	Url           string
+	Source        string
+	Headers       map[string]string
+	BatchSize     int
+	FlushInterval time.Duration
+	QueueSize     int
+	Retries       int
+	Backoff       time.Duration
+	Timeout       time.Duration
+}
+
+// Sink will deliver events asynchronously to a http endpoint.
+type Sink struct {
+	config Config
+	client *http.Client
+	queue  chan Event
+	stop   chan bool
+	done   chan bool
+}
+
+// StatusError is the error that is returned when the endpoint of the sink
+// responds with a non 2xx status code.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+// Error will return the error message, as required by the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("error posting events; status=%s", e.Status)
+}
+
+// NewSink will instantiate a new Sink for given config, applying the default
+// settings for the settings that are not set.
+func NewSink(cfg Config) *Sink {
+	if cfg.Source == "" {
+		cfg.Source = "nightshift"
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 10
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = 1000
+	}
+	if cfg.Retries < 0 {
+		cfg.Retries = 0
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &Sink{
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		queue:  make(chan Event, cfg.QueueSize),
+		stop:   make(chan bool),
+		done:   make(chan bool),
+	}
+}
+
+// Start will start delivering the published events in the background.
+func (s *Sink) Start() {
+	go s.run()
+}
+
+// Stop will stop the sink, after delivering the events that are queued. It
+// will block until these events are delivered, or failed.
+func (s *Sink) Stop() {
+	close(s.stop)
+	<-s.done
+}
+
+// Publish will queue the given event for delivery. It will not block; if the
+// queue is full, the event is dropped, and false is returned.
+func (s *Sink) Publish(evt Event) bool {
+	select {
+	case s.queue <- evt:
+		return true
+	default:
+		glog.Warningf("Dropped event %s for %s: queue is full", evt.Type, s.config.Url)
+		metrics.EventsPublished(s.config.Url, outcomeDropped, 1)
+		return false
+	}
+}
+
+// run will collect the queued events in batches, and deliver them when the
+// batch is full, or the flush interval has passed.
+func (s *Sink) run() {
+	defer close(s.done)
+	tick := time.NewTicker(s.config.FlushInterval)
+	defer tick.Stop()
+	batch := []Event{}
+	for {
+		select {
+		case evt := <-s.queue:
+			batch = append(batch, evt)
+			if len(batch) >= s.config.BatchSize {
+				s.deliver(batch)
+				batch = []Event{}
+			}
+		case <-tick.C:
+			if len(batch) > 0 {
+				s.deliver(batch)
+				batch = []Event{}
+			}
+		case <-s.stop:
+			s.flush(batch)
+			return
+		}
+	}
+}
+
+// flush will deliver the given batch, together with the events that are
+// still queued, in batches of at most the configured batch size.
+func (s *Sink) flush(batch []Event) {
+	for len(s.queue) > 0 {
+		batch = append(batch, <-s.queue)
+	}
+	for len(batch) > 0 {
+		n := len(batch)
+		if n > s.config.BatchSize {
+			n = s.config.BatchSize
+		}
+		s.deliver(batch[:n])
+		batch = batch[n:]
+	}
+}
+
+// deliver will post the given batch of events, and retry as configured if
+// this failed.
+func (s *Sink) deliver(batch []Event) {
+	delay := s.config.Backoff
+	for attempt := 0; ; attempt++ {
+		err := s.post(batch)
+		if err == nil {
+			metrics.EventsPublished(s.config.Url, outcomeDelivered, len(batch))
+			return
+		}
+		if attempt >= s.config.Retries || !shouldRetry(err) {
+			glog.Errorf("Error delivering %d events to %s: %s", len(batch), s.config.Url, err)
+			metrics.EventsPublished(s.config.Url, outcomeFailed, len(batch))
+			return
+		}
+		glog.V(4).Infof("Retrying delivery of events to %s in %s: %s", s.config.Url, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// shouldRetry will return true if the delivery that failed with given error
+// should be retried; this is the case for connection errors, server errors,
+// and when the endpoint is throttling.
+func shouldRetry(err error) bool {
+	serr, ok := err.(*StatusError)
+	if !ok {
+		return true
+	}
+	return serr.StatusCode >= 500 || serr.StatusCode == http.StatusTooManyRequests
+}
+
+// post will post the given events. If the batch size is 1, the event is
+// posted in the structured mode, otherwise the events are posted in the
+// batched mode.
+func (s *Sink) post(batch []Event) error {
+	var body interface{} = batch
+	ctype := "application/cloudevents-batch+json"
+	if s.config.BatchSize == 1 && len(batch) == 1 {
+		body = batch[0]
+		ctype = "application/cloudevents+json"
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", s.config.Url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	for k, v := range s.config.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", ctype)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{Status: resp.Status, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package events
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joyrex2001/nightshift/internal/activity"
+)
+
+// fakeReceiver is a http endpoint that records the received events, and
+// responds with the configured status codes.
+type fakeReceiver struct {
+	m        sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+// receivedRequest is a request as received by the fakeReceiver.
+type receivedRequest struct {
+	ctype  string
+	header string
+	events []Event
+}
+
+func (f *fakeReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	req := receivedRequest{
+		ctype:  r.Header.Get("Content-Type"),
+		header: r.Header.Get("Authorization"),
+	}
+	data, _ := ioutil.ReadAll(r.Body)
+	if req.ctype == "application/cloudevents+json" {
+		evt := Event{}
+		json.Unmarshal(data, &evt)
+		req.events = []Event{evt}
+	} else {
+		json.Unmarshal(data, &req.events)
+	}
+	f.requests = append(f.requests, req)
+	if len(f.statuses) > 0 {
+		w.WriteHeader(f.statuses[0])
+		f.statuses = f.statuses[1:]
+	}
+}
+
+func (f *fakeReceiver) received() []receivedRequest {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return append([]receivedRequest{}, f.requests...)
+}
+
+func newTestEvent(obj string) Event {
+	return NewEvent(obj, "nightshift", activity.Record{Action: "scale", Namespace: "dev", Object: obj})
+}
+
+func TestSink(t *testing.T) {
+	tests := []struct {
+		config   Config
+		statuses []int
+		publish  int
+		batches  []int
+		ctype    string
+	}{
+		{
+			config:  Config{BatchSize: 2, FlushInterval: time.Hour},
+			publish: 5,
+			batches: []int{2, 2, 1},
+			ctype:   "application/cloudevents-batch+json",
+		},
+		{
+			config:  Config{BatchSize: 1, FlushInterval: time.Hour},
+			publish: 2,
+			batches: []int{1, 1},
+			ctype:   "application/cloudevents+json",
+		},
+		{
+			config:   Config{BatchSize: 3, FlushInterval: time.Hour, Retries: 2, Backoff: time.Millisecond},
+			statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			publish:  3,
+			batches:  []int{3, 3, 3},
+			ctype:    "application/cloudevents-batch+json",
+		},
+		{
+			config:   Config{BatchSize: 3, FlushInterval: time.Hour, Retries: 1, Backoff: time.Millisecond},
+			statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			publish:  3,
+			batches:  []int{3, 3},
+			ctype:    "application/cloudevents-batch+json",
+		},
+		{
+			config:   Config{BatchSize: 3, FlushInterval: time.Hour, Retries: 3, Backoff: time.Millisecond},
+			statuses: []int{http.StatusBadRequest},
+			publish:  3,
+			batches:  []int{3},
+			ctype:    "application/cloudevents-batch+json",
+		},
+	}
+	for i, tst := range tests {
+		rcv := &fakeReceiver{statuses: tst.statuses}
+		srv := httptest.NewServer(rcv)
+		tst.config.Url = srv.URL
+		tst.config.Headers = map[string]string{"Authorization": "Bearer token"}
+		snk := NewSink(tst.config)
+		snk.Start()
+		for j := 0; j < tst.publish; j++ {
+			snk.Publish(newTestEvent("app"))
+		}
+		snk.Stop()
+		srv.Close()
+		reqs := rcv.received()
+		if len(reqs) != len(tst.batches) {
+			t.Errorf("failed test %d - expected %d requests, got %d", i, len(tst.batches), len(reqs))
+			continue
+		}
+		for j, req := range reqs {
+			if len(req.events) != tst.batches[j] {
+				t.Errorf("failed test %d - expected %d events in request %d, got %d", i, tst.batches[j], j, len(req.events))
+			}
+			if req.ctype != tst.ctype {
+				t.Errorf("failed test %d - expected content type %s, got %s", i, tst.ctype, req.ctype)
+			}
+			if req.header != "Bearer token" {
+				t.Errorf("failed test %d - expected authorization header, got %q", i, req.header)
+			}
+		}
+	}
+}
+
+func TestSinkFlushInterval(t *testing.T) {
+	rcv := &fakeReceiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+	snk := NewSink(Config{Url: srv.URL, BatchSize: 10, FlushInterval: 10 * time.Millisecond})
+	snk.Start()
+	defer snk.Stop()
+	snk.Publish(newTestEvent("app"))
+	snk.Publish(newTestEvent("db"))
+	for i := 0; i < 100 && len(rcv.received()) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	reqs := rcv.received()
+	if len(reqs) != 1 || len(reqs[0].events) != 2 {
+		t.Errorf("failed test - expected 1 request with 2 events, got %v", reqs)
+	}
+}
+
+func TestSinkQueueFull(t *testing.T) {
+	rcv := &fakeReceiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+	// the sink is not started, so published events are only queued
+	snk := NewSink(Config{Url: srv.URL, QueueSize: 2, BatchSize: 10, FlushInterval: time.Hour})
+	for i, exp := range []bool{true, true, false} {
+		if ok := snk.Publish(newTestEvent("app")); ok != exp {
+			t.Errorf("failed test %d - expected %t, got %t", i, exp, ok)
+		}
+	}
+	snk.Start()
+	snk.Stop()
+	reqs := rcv.received()
+	if len(reqs) != 1 || len(reqs[0].events) != 2 {
+		t.Errorf("failed test - expected 1 request with 2 events, got %v", reqs)
+	}
+}
@@ -0,0 +1,101 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/joyrex2001/nightshift/internal/agent"
+	"github.com/joyrex2001/nightshift/internal/config"
+)
+
+// Explain will scan all objects according to the configuration, and print
+// how the effective schedule has been determined for the objects that match
+// the given arguments. Each argument is either an uid, or namespace/name.
+func Explain(cmd *cobra.Command, args []string) {
+	setTimeZone()
+	agt := agent.New()
+	cfg := loadConfig()
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	addClusters(cfg)
+	if viper.GetBool("openshift.schedule-crd") {
+		pollResources()
+	}
+	// explain should not change the cluster, so the status of the
+	// NightshiftSchedule resources is left untouched
+	errs := addScanners(agt, cfg, false)
+	printScannerErrors(os.Stdout, errs)
+	agt.UpdateSchedule()
+	found := false
+	for _, uid := range findObjects(agt, args) {
+		expl, err := agt.Explain(uid)
+		if err != nil {
+			glog.Errorf("Error explaining %s: %s", uid, err)
+			continue
+		}
+		printExplanation(os.Stdout, expl)
+		found = true
+	}
+	if !found {
+		fmt.Printf("No objects found for: %s\n", strings.Join(args, ", "))
+	}
+}
+
+// printScannerErrors will write the errors of the scanners that could not be
+// added to given writer, as objects of these scanners are missing from the
+// explanation.
+func printScannerErrors(out io.Writer, errs []error) {
+	if len(errs) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "Failed scanners (objects of these scanners are not explained):\n")
+	for _, err := range errs {
+		fmt.Fprintf(out, "  %s\n", err)
+	}
+}
+
+// findObjects will return the uids of the known objects that match any of
+// the given uid or namespace/name arguments.
+func findObjects(agt agent.Agent, args []string) []string {
+	uids := []string{}
+	for uid, obj := range agt.GetObjects() {
+		for _, arg := range args {
+			if arg == uid || arg == obj.Namespace+"/"+obj.Name {
+				uids = append(uids, uid)
+				break
+			}
+		}
+	}
+	return uids
+}
+
+// printExplanation will write a human readable version of the given
+// explanation to given writer.
+func printExplanation(out io.Writer, expl *agent.Explanation) {
+	fmt.Fprintf(out, "-------------------------------------------------\n")
+	fmt.Fprintf(out, "object:   %s/%s (%s, %s)\n", expl.Namespace, expl.Name, expl.Type, expl.UID)
+	if expl.Cluster != "" {
+		fmt.Fprintf(out, "cluster:  %s\n", expl.Cluster)
+	}
+	fmt.Fprintf(out, "override: %s\n", expl.Override)
+	fmt.Fprintf(out, "schedule: %s\n", strings.Join(expl.Schedule, "; "))
+	fmt.Fprintf(out, "matches:\n")
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "\tPRIORITY\tID\tSELECTOR\tSCHEDULE\n")
+	for _, m := range expl.Matches {
+		eff := ""
+		if m.Effective {
+			eff = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", eff, m.Priority, m.ScannerId, m.Selector, strings.Join(m.Schedule, "; "))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/joyrex2001/nightshift/internal/agent"
+)
+
+func TestPrintExplanation(t *testing.T) {
+	expl := &agent.Explanation{
+		UID:       "abc",
+		Cluster:   "dev",
+		Namespace: "development",
+		Name:      "shell",
+		Override:  "none",
+		Schedule:  []string{"mon-fri 9:00 replicas=1", "mon-fri 18:00 replicas=0"},
+		Matches: []agent.Match{
+			{ScannerId: "shell", Priority: 2, Selector: "app=shell", Effective: true},
+			{ScannerId: "default", Priority: 0},
+		},
+	}
+	buf := new(bytes.Buffer)
+	printExplanation(buf, expl)
+	out := buf.String()
+	for _, exp := range []string{"cluster:  dev", "development/shell", "mon-fri 9:00 replicas=1; mon-fri 18:00 replicas=0", "app=shell", "default"} {
+		if !strings.Contains(out, exp) {
+			t.Errorf("failed test - expected %q in output:\n%s", exp, out)
+		}
+	}
+}
+
+func TestPrintScannerErrors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	printScannerErrors(buf, nil)
+	if buf.Len() != 0 {
+		t.Errorf("failed test - expected no output without errors, got %s", buf.String())
+	}
+	printScannerErrors(buf, []error{errors.New("scanner prod/development: unknown cluster: prod")})
+	if out := buf.String(); !strings.Contains(out, "Failed scanners") || !strings.Contains(out, "scanner prod/development: unknown cluster: prod") {
+		t.Errorf("failed test - expected the failed scanner in output:\n%s", out)
+	}
+}
@@ -1,14 +1,19 @@
 package internal
 
 import (
+	"fmt"
+	"sync"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/golang/glog"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
+	"github.com/joyrex2001/nightshift/internal/activity"
 	"github.com/joyrex2001/nightshift/internal/agent"
 	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/events"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/schedule"
 	"github.com/joyrex2001/nightshift/internal/trigger"
@@ -19,61 +24,226 @@ import (
 // rock the boat.
 func Main(cmd *cobra.Command, args []string) {
 	// generic initialization
+	setTimeZone()
+	setKubernetesAccess()
+	// start subsystems
+	startActivity()
+	startAgent()
+	startWebUI()
+	forever()
+}
+
+// setTimeZone will configure the timezone in which the schedules are defined.
+func setTimeZone() {
 	tz := viper.GetString("generic.timezone")
 	if err := schedule.SetTimeZone(tz); err != nil {
 		glog.Errorf("Invalid timezone specified: %s", err)
 	} else {
 		glog.Infof("Using timezone: %s", tz)
 	}
-	// start subsystems
-	startAgent()
-	startWebUI()
-	forever()
+}
+
+// setKubernetesAccess will configure triggers to read the kubernetes secrets
+// they refer to from the default cluster, and to use the configured clusters
+// to e.g. create jobs.
+func setKubernetesAccess() {
+	trigger.SetSecretGetter(func(namespace, name, key string) (string, error) {
+		return scanner.GetSecret("", namespace, name, key)
+	})
+	trigger.SetKubernetesGetter(scanner.GetKubernetes)
+}
+
+// startActivity will configure the activity journal, and publish its
+// records to the configured event sinks.
+func startActivity() {
+	activity.SetSize(viper.GetInt("activity.size"))
+	activity.Subscribe(events.Publish)
+	if file := viper.GetString("activity.file"); file != "" {
+		if err := activity.SetFile(file); err != nil {
+			glog.Errorf("Error opening activity file: %s", err)
+		}
+	}
 }
 
 // startAgent will start the agent that will monitor and scale the openshift
 // resources according to the schedules.
 func startAgent() {
 	agt := agent.New()
-	if cfg := loadConfig(); cfg != nil {
-		addScanners(agt, cfg)
+	cfg := loadConfig()
+	if cfg == nil && viper.ConfigFileUsed() != "" && viper.GetBool("generic.strict-config") {
+		glog.Exitf("Refusing to start with invalid config file %s", viper.ConfigFileUsed())
+	}
+	if cfg != nil {
+		applied = cfg
+		addClusters(cfg)
+		addEvents(cfg)
+		addScanners(agt, cfg, true)
 		addTriggers(agt, cfg)
 	}
 	interval := viper.GetDuration("generic.interval")
 	agt.SetResyncInterval(interval)
+	agt.SetScaleWorkers(viper.GetInt("generic.scale-workers"))
 	agt.Start()
+	if viper.GetBool("openshift.schedule-crd") {
+		startResources(agt, viper.GetDuration("openshift.schedule-crd-interval"))
+	}
+	if viper.GetBool("generic.watch-config") && viper.ConfigFileUsed() != "" {
+		viper.OnConfigChange(func(e fsnotify.Event) {
+			glog.Infof("Config file changed: %s", e.Name)
+			reloadConfig(agt)
+		})
+		viper.WatchConfig()
+	}
+}
+
+// registry is implemented by anything that can collect scanners and triggers;
+// the agent itself, as well as the staging area used when reloading the
+// configuration.
+type registry interface {
+	AddScanner(scanner.Scanner)
+	AddTrigger(string, trigger.Trigger)
 }
 
-// loadConfig will load the nightshift configuration from the configfile.
+// staging will collect scanners and triggers while reloading the
+// configuration, so they can be swapped into the agent at once.
+type staging struct {
+	scanners []scanner.Scanner
+	triggers map[string]trigger.Trigger
+}
+
+// AddScanner will add a scanner to the staging area.
+func (s *staging) AddScanner(scnr scanner.Scanner) {
+	s.scanners = append(s.scanners, scnr)
+}
+
+// AddTrigger will add a trigger to the staging area.
+func (s *staging) AddTrigger(id string, trgr trigger.Trigger) {
+	s.triggers[id] = trgr
+}
+
+var (
+	// applied is the configuration that is currently applied to the agent.
+	applied = &config.Config{}
+	cfgmu   sync.Mutex
+)
+
+// reloadConfig will re-read the configuration file and replace the scanners
+// and triggers of the given agent. If the new configuration can't be loaded,
+// the current configuration will be kept.
+func reloadConfig(agt agent.Agent) {
+	cfg := loadConfig()
+	if cfg == nil {
+		glog.Errorf("Reload aborted, keeping current configuration")
+		return
+	}
+	addClusters(cfg)
+	addEvents(cfg)
+	applyConfig(agt, cfg)
+}
+
+// reloadResources will replace the scanners and triggers of the given agent,
+// using the currently applied configuration and the NightshiftSchedule
+// resources that were found during the last poll.
+func reloadResources(agt agent.Agent) {
+	cfgmu.Lock()
+	cfg := applied
+	cfgmu.Unlock()
+	applyConfig(agt, cfg)
+}
+
+// applyConfig will replace the scanners and triggers of the given agent with
+// the ones in the given configuration, and the NightshiftSchedule resources.
+func applyConfig(agt agent.Agent, cfg *config.Config) {
+	cfgmu.Lock()
+	defer cfgmu.Unlock()
+	applied = cfg
+	stg := &staging{
+		scanners: []scanner.Scanner{},
+		triggers: map[string]trigger.Trigger{},
+	}
+	addScanners(stg, cfg, true)
+	addTriggers(stg, cfg)
+	agt.Reload(stg.scanners, stg.triggers)
+}
+
+// loadConfig will load the nightshift configuration from the configfile. If
+// parts of the configuration are invalid, the errors are logged and the valid
+// parts are returned, unless strict-config is enabled, in which case nil is
+// returned.
 func loadConfig() *config.Config {
 	if viper.ConfigFileUsed() != "" {
 		cfg, err := config.New(viper.ConfigFileUsed())
 		if err != nil {
 			glog.Errorf("Error parsing config: %s", err)
-			return nil
+			if cfg == nil || viper.GetBool("generic.strict-config") {
+				return nil
+			}
+			glog.Warningf("Ignoring the invalid parts of config file %s", viper.ConfigFileUsed())
 		}
 		return cfg
 	}
 	return nil
 }
 
+// addClusters will make the configured named clusters available to the
+// scanners.
+func addClusters(cfg *config.Config) {
+	cls := []scanner.Cluster{}
+	for _, cl := range cfg.Clusters {
+		glog.V(5).Infof("Adding cluster: %v", cl)
+		cls = append(cls, scanner.Cluster{
+			Name:       cl.Name,
+			Kubeconfig: cl.Kubeconfig,
+			Context:    cl.Context,
+		})
+	}
+	scanner.SetClusters(cls)
+}
+
+// addEvents will start the configured event sinks, replacing the current
+// sinks.
+func addEvents(cfg *config.Config) {
+	sinks := []events.Config{}
+	for _, evt := range cfg.Events {
+		glog.V(5).Infof("Adding event sink: %s", evt.Url)
+		sinks = append(sinks, events.Config{
+			Url:           evt.Url,
+			Source:        evt.Source,
+			Headers:       evt.Headers,
+			BatchSize:     evt.BatchSize,
+			FlushInterval: evt.GetFlushInterval(),
+			QueueSize:     evt.QueueSize,
+			Retries:       evt.GetRetries(),
+			Backoff:       evt.GetBackoff(),
+			Timeout:       evt.GetTimeout(),
+		})
+	}
+	events.SetSinks(sinks)
+}
+
 // addScanners will add configured scanners to the provided agent. The scanners
-// are added in the order of priority, lowest priority is added first.
-func addScanners(agent agent.Agent, cfg *config.Config) {
+// are added in the order of priority, lowest priority is added first. The
+// scanners of the NightshiftSchedule resources are added last, and take
+// precedence over the configured scanners. If status is true, the outcome of
+// the validation of the resources is written to their status. It will return
+// the errors of the scanners that could not be added.
+func addScanners(agent registry, cfg *config.Config, status bool) []error {
 	// go through configured scanners
+	errs := []error{}
 	prio := 0
 	for _, scan := range cfg.Scanner {
 		glog.V(5).Infof("Adding scanner: %v", scan)
 		def, _ := scan.Default.GetSchedule()
 		// add namespace scanner
 		for _, ns := range scan.Namespace {
-			addScanner(agent, scanner.Config{
+			errs = appendError(errs, addScanner(agent, scanner.Config{
 				Id:        scan.Default.Id,
 				Type:      scan.Type,
+				Cluster:   scan.Cluster,
 				Namespace: ns,
 				Schedule:  def,
 				Priority:  prio,
-			})
+			}))
 			prio++
 		}
 		// add exceptions specified in deployments
@@ -81,40 +251,67 @@ func addScanners(agent agent.Agent, cfg *config.Config) {
 			sched, _ := depl.GetSchedule()
 			for _, ns := range scan.Namespace {
 				for _, sel := range depl.Selector {
-					addScanner(agent, scanner.Config{
+					errs = appendError(errs, addScanner(agent, scanner.Config{
 						Id:        depl.Id,
 						Type:      scan.Type,
+						Cluster:   scan.Cluster,
 						Namespace: ns,
 						Schedule:  sched,
 						Label:     sel,
 						Priority:  prio,
-					})
+					}))
 					prio++
 				}
 			}
 		}
 	}
+	return append(errs, addResourceScanners(agent, cfg, prio, status)...)
 }
 
 // addScanner will add a scanner specified with the scanner.Config object to
-// the given agent.
-func addScanner(agent agent.Agent, cfg scanner.Config) {
+// the given agent. It will return an error if the scanner could not be
+// created, e.g. because it failed to connect to its cluster.
+func addScanner(agent registry, cfg scanner.Config) error {
 	scanr, err := scanner.NewForConfig(cfg)
 	if err != nil {
+		err = fmt.Errorf("scanner %s: %s", scannerName(cfg), err)
 		glog.Errorf("Error adding scanners: %s", err)
-		return
+		return err
 	}
 	agent.AddScanner(scanr)
+	return nil
+}
+
+// scannerName will return a description of the scanner with given config,
+// for use in error messages.
+func scannerName(cfg scanner.Config) string {
+	name := cfg.Namespace
+	if cfg.Label != "" {
+		name += " (" + cfg.Label + ")"
+	}
+	if cfg.Cluster != "" {
+		name = cfg.Cluster + "/" + name
+	}
+	return name
+}
+
+// appendError will append the given error to the list of errors, if not nil.
+func appendError(errs []error, err error) []error {
+	if err != nil {
+		return append(errs, err)
+	}
+	return errs
 }
 
 // addTriggers will add configured triggers to the provided agent.
-func addTriggers(agent agent.Agent, cfg *config.Config) {
+func addTriggers(agent registry, cfg *config.Config) {
 	for _, def := range cfg.Trigger {
 		trgr, err := trigger.New(def.Type)
 		if err != nil {
 			glog.Errorf("Error adding trigger: %s", err)
 		} else {
-			trgr.SetConfig(trigger.Config{Id: def.Id, Type: def.Type, Settings: def.Config})
+			sched, _ := def.GetSchedule()
+			trgr.SetConfig(trigger.Config{Id: def.Id, Type: def.Type, Settings: def.Config, Schedule: sched})
 			agent.AddTrigger(def.Id, trgr)
 		}
 	}
@@ -1,11 +1,21 @@
 package internal
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/joyrex2001/nightshift/internal/agent"
 	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/crd"
 	"github.com/joyrex2001/nightshift/internal/scanner"
 	"github.com/joyrex2001/nightshift/internal/trigger"
 )
@@ -16,8 +26,9 @@ type scinfo struct {
 }
 
 type mockAgent struct {
-	trgrs []string
-	scnrs []scinfo
+	trgrs    []string
+	scnrs    []scinfo
+	reloaded bool
 }
 
 func NewMockAgent() *mockAgent {
@@ -28,6 +39,7 @@ func NewMockAgent() *mockAgent {
 }
 
 func (a *mockAgent) SetResyncInterval(t time.Duration) {}
+func (a *mockAgent) SetScaleWorkers(n int)             {}
 func (a *mockAgent) UpdateSchedule()                   {}
 func (a *mockAgent) Start()                            {}
 func (a *mockAgent) Stop()                             {}
@@ -41,6 +53,14 @@ func (a *mockAgent) AddTrigger(id string, trgr trigger.Trigger) {
 	a.trgrs = append(a.trgrs, id)
 }
 
+func (a *mockAgent) Reload(scnrs []scanner.Scanner, trgrs map[string]trigger.Trigger) {
+	a.reloaded = true
+}
+
+func (a *mockAgent) Explain(uid string) (*agent.Explanation, error) {
+	return nil, nil
+}
+
 func (a *mockAgent) GetObjects() map[string]*scanner.Object {
 	objs := map[string]*scanner.Object{}
 	return objs
@@ -56,14 +76,24 @@ func (a *mockAgent) GetTriggers() map[string]trigger.Trigger {
 	return res
 }
 
+func (a *mockAgent) GetDeadLetters() []agent.DeadLetter {
+	return []agent.DeadLetter{}
+}
+
+func (a *mockAgent) RetryTrigger(id string) (int, error) {
+	return 0, nil
+}
+
+func (a *mockAgent) Wake(objs []trigger.EventObject) {}
+
 type mockTrigger struct {
 	id  string
 	cfg trigger.Config
 }
 
-func (m *mockTrigger) SetConfig(c trigger.Config) { m.cfg = c }
-func (m *mockTrigger) GetConfig() trigger.Config  { return m.cfg }
-func (m *mockTrigger) Execute() error             { return nil }
+func (m *mockTrigger) SetConfig(c trigger.Config)  { m.cfg = c }
+func (m *mockTrigger) GetConfig() trigger.Config   { return m.cfg }
+func (m *mockTrigger) Execute(trigger.Event) error { return nil }
 
 func getTriggerFactory(typ string, m *mockTrigger) trigger.Factory {
 	return func() (trigger.Trigger, error) {
@@ -80,6 +110,7 @@ func (m *mockScanner) SetConfig(c scanner.Config)                        { m.cfg
 func (m *mockScanner) GetConfig() scanner.Config                         { return m.cfg }
 func (m *mockScanner) GetObjects() ([]*scanner.Object, error)            { return []*scanner.Object{}, nil }
 func (m *mockScanner) SaveState(obj *scanner.Object) (int, error)        { return 0, nil }
+func (m *mockScanner) Snooze(obj *scanner.Object, u *time.Time) error    { return nil }
 func (m *mockScanner) Scale(obj *scanner.Object, r int) error            { return nil }
 func (m *mockScanner) Watch(_stop chan bool) (chan scanner.Event, error) { return nil, nil }
 
@@ -98,6 +129,30 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestReloadConfig(t *testing.T) {
+	agt := NewMockAgent()
+	reloadConfig(agt)
+	if agt.reloaded {
+		t.Errorf("expected agent not to be reloaded due to missing configfile")
+	}
+}
+
+func TestStaging(t *testing.T) {
+	stg := &staging{
+		scanners: []scanner.Scanner{},
+		triggers: map[string]trigger.Trigger{},
+	}
+	addTriggers(stg, &config.Config{
+		Trigger: []*config.Trigger{
+			{Id: "build", Type: "webhook"},
+			{Id: "dummy", Type: "dummyerror"},
+		},
+	})
+	if len(stg.triggers) != 1 || stg.triggers["build"] == nil {
+		t.Errorf("expected staging to contain trigger build, got %v", stg.triggers)
+	}
+}
+
 func TestAddTriggers(t *testing.T) {
 	tests := []struct {
 		in  *config.Config
@@ -135,8 +190,9 @@ func TestAddTriggers(t *testing.T) {
 
 func TestAddAgents(t *testing.T) {
 	tests := []struct {
-		in  *config.Config
-		out []scinfo
+		in   *config.Config
+		out  []scinfo
+		errs []string
 	}{
 		{
 			in:  &config.Config{},
@@ -188,7 +244,8 @@ func TestAddAgents(t *testing.T) {
 					},
 				},
 			},
-			out: []scinfo{{"mockscanner", 0}, {"mockscanner", 1}, {"mockscanner", 2}, {"mockscanner", 3}, {"mockscanner", 4}},
+			out:  []scinfo{{"mockscanner", 0}, {"mockscanner", 1}, {"mockscanner", 2}, {"mockscanner", 3}, {"mockscanner", 4}},
+			errs: []string{"scanner batch: "},
 		},
 	}
 
@@ -197,9 +254,88 @@ func TestAddAgents(t *testing.T) {
 
 	for i, tst := range tests {
 		agt := NewMockAgent()
-		addScanners(agt, tst.in)
+		errs := addScanners(agt, tst.in, true)
 		if !reflect.DeepEqual(agt.scnrs, tst.out) {
 			t.Errorf("failed %d - expected %v, got %v", i, tst.out, agt.scnrs)
 		}
+		if len(errs) != len(tst.errs) {
+			t.Errorf("failed %d - expected errors %v, got %v", i, tst.errs, errs)
+			continue
+		}
+		for j, err := range errs {
+			if !strings.HasPrefix(err.Error(), tst.errs[j]) {
+				t.Errorf("failed %d - expected error %q, got %q", i, tst.errs[j], err)
+			}
+		}
+	}
+}
+
+func TestAddResourceScanners(t *testing.T) {
+	statuses := map[string]crd.Status{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		patch := struct {
+			Status crd.Status `json:"status"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&patch)
+		statuses[r.URL.Path] = patch.Status
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	}))
+	defer srv.Close()
+	crd.SetKubernetesGetter(func(cluster string) (*rest.Config, error) {
+		return &rest.Config{Host: srv.URL}, nil
+	})
+	defer crd.SetKubernetesGetter(scanner.GetKubernetes)
+
+	mock := &mockScanner{}
+	scanner.RegisterModule("mockscanner", getScannerFactory("mockscanner", mock))
+	newResource := func(name, selector string, sched ...string) *crd.NightshiftSchedule {
+		return &crd.NightshiftSchedule{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "dev", Name: name, Generation: 3},
+			Spec:       crd.Spec{Type: "mockscanner", Selector: selector, Schedule: sched},
+		}
+	}
+	resources = map[string][]*crd.NightshiftSchedule{
+		"": {
+			newResource("web", "app=web", "Mon-Fri 9:00 replicas=2 trigger=notify"),
+			newResource("default", "", "Mon-Fri 9:00 replicas=1"),
+			newResource("invalid", "", "Mon-Fri 9:00 replicas=1 trigger=unknown"),
+		},
+	}
+	defer func() { resources = map[string][]*crd.NightshiftSchedule{} }()
+
+	cfg := &config.Config{
+		Trigger: []*config.Trigger{{Id: "notify", Type: "webhook"}},
+		Scanner: []*config.Scanner{
+			{
+				Namespace: []string{"dev"},
+				Default:   &config.Default{Schedule: []string{"Mon-Fri 9:00 replicas=1"}},
+				Type:      "mockscanner",
+			},
+		},
+	}
+	exp := []scinfo{{"mockscanner", 0}, {"mockscanner", 1}, {"mockscanner", 2}}
+
+	// without status, e.g. when explaining, the resources are left untouched
+	agt := NewMockAgent()
+	addScanners(agt, cfg, false)
+	if !reflect.DeepEqual(agt.scnrs, exp) {
+		t.Errorf("failed test - expected %v, got %v", exp, agt.scnrs)
+	}
+	if len(statuses) != 0 {
+		t.Errorf("failed test - expected no status updates, got %v", statuses)
+	}
+
+	agt = NewMockAgent()
+	addScanners(agt, cfg, true)
+	if !reflect.DeepEqual(agt.scnrs, exp) {
+		t.Errorf("failed test - expected %v, got %v", exp, agt.scnrs)
+	}
+	path := "/apis/" + crd.Group + "/" + crd.Version + "/namespaces/dev/" + crd.Resource + "/%s/status"
+	for name, valid := range map[string]bool{"default": true, "web": true, "invalid": false} {
+		status := statuses[fmt.Sprintf(path, name)]
+		if status.Valid != valid || status.ObservedGeneration != 3 || (status.Message == "") == !valid {
+			t.Errorf("failed test - unexpected status for %s: %v", name, status)
+		}
 	}
 }
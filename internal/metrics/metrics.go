@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"time"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -30,9 +32,18 @@ var (
 		"manual_restore_error": {
 			Help: "The total number of errors while manual restoring",
 		},
+		"hook_veto": {
+			Help: "The total number of scale events skipped because a before hook failed",
+		},
 		"resync_error": {
 			Help: "The total number errors while resyncing objects",
 		},
+		"api_retryable_error": {
+			Help: "The total number of retryable errors from the kubernetes api",
+		},
+		"api_permanent_error": {
+			Help: "The total number of permanent errors from the kubernetes api",
+		},
 		"watch_retries": {
 			Help: "The total number of watcher connection retries",
 		},
@@ -40,6 +51,38 @@ var (
 			Help: "The total number of error events received from watcher connection",
 		},
 	}
+	// custom metric for exporting the outcome of trigger executions
+	triggers = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsPrefix + "trigger_executions_total",
+			Help: "The total number of trigger executions per trigger and outcome",
+		},
+		[]string{"trigger", "outcome"},
+	)
+	// custom metric for exporting how trigger executions were queued
+	queued = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsPrefix + "trigger_queue_total",
+			Help: "The total number of queued, coalesced, dropped and rejected trigger executions",
+		},
+		[]string{"trigger", "outcome"},
+	)
+	// custom metric for exporting the outcome of publishing events
+	events = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: metricsPrefix + "events_total",
+			Help: "The total number of delivered, failed and dropped events per sink",
+		},
+		[]string{"sink", "outcome"},
+	)
+	// custom metric for exporting the duration of a scale tick
+	scaleTick = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    metricsPrefix + "scale_tick_duration_seconds",
+			Help:    "The duration of processing all objects that are due in a scale tick",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 15),
+		},
+	)
 	// custom metric for exporting current number of replicas
 	replicas = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -59,6 +102,10 @@ func init() {
 		prometheus.MustRegister(m.prom)
 	}
 	prometheus.MustRegister(replicas)
+	prometheus.MustRegister(scaleTick)
+	prometheus.MustRegister(triggers)
+	prometheus.MustRegister(queued)
+	prometheus.MustRegister(events)
 }
 
 // Increase will increase given metric with 1
@@ -69,6 +116,39 @@ func Increase(metr string) {
 	}
 }
 
+// TriggerExecuted will count the execution of the trigger with given id as
+// either a success or a failure, depending on the given error.
+func TriggerExecuted(id string, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	triggers.With(prometheus.Labels{
+		"trigger": id,
+		"outcome": outcome}).Inc()
+}
+
+// TriggerQueued will count the outcome of queueing an execution of the
+// trigger with given id.
+func TriggerQueued(id, outcome string) {
+	queued.With(prometheus.Labels{
+		"trigger": id,
+		"outcome": outcome}).Inc()
+}
+
+// EventsPublished will count the given number of events for the sink with
+// given url, with the given outcome.
+func EventsPublished(sink, outcome string, n int) {
+	events.With(prometheus.Labels{
+		"sink":    sink,
+		"outcome": outcome}).Add(float64(n))
+}
+
+// ObserveScaleTick will record the duration of a scale tick.
+func ObserveScaleTick(d time.Duration) {
+	scaleTick.Observe(d.Seconds())
+}
+
 // SetReplicas will set the replicas metric to given value for given namespace
 // and scanner id.
 func SetReplicas(ns, scanid string, repl int) {
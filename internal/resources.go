@@ -0,0 +1,122 @@
+package internal
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/joyrex2001/nightshift/internal/agent"
+	"github.com/joyrex2001/nightshift/internal/config"
+	"github.com/joyrex2001/nightshift/internal/crd"
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+var (
+	// resources contains the NightshiftSchedule resources per cluster, as
+	// found during the last poll.
+	resources = map[string][]*crd.NightshiftSchedule{}
+	resmu     sync.Mutex
+)
+
+// startResources will poll the NightshiftSchedule resources at the given
+// interval, and reload the agent when these have been added, removed or
+// changed.
+func startResources(agt agent.Agent, interval time.Duration) {
+	go func() {
+		for {
+			if pollResources() {
+				glog.Infof("NightshiftSchedule resources changed")
+				reloadResources(agt)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// pollResources will list the NightshiftSchedule resources in the default
+// cluster and the configured clusters. It will return true if these have
+// changed since the previous poll. If listing fails for a cluster, the
+// resources that were found previously for that cluster are kept.
+func pollResources() bool {
+	clusters := []string{""}
+	for _, cl := range scanner.GetClusters() {
+		clusters = append(clusters, cl.Name)
+	}
+	found := map[string][]*crd.NightshiftSchedule{}
+	resmu.Lock()
+	defer resmu.Unlock()
+	for _, cl := range clusters {
+		scheds, err := crd.List(cl)
+		if err != nil {
+			glog.Errorf("Error listing NightshiftSchedule resources in cluster '%s': %s", cl, err)
+			if prev, ok := resources[cl]; ok {
+				found[cl] = prev
+			}
+			continue
+		}
+		found[cl] = scheds
+	}
+	changed := !reflect.DeepEqual(resourceKeys(resources), resourceKeys(found))
+	resources = found
+	return changed
+}
+
+// resourceKeys will return the keys of the given resources, which will change
+// if a resource is added, removed or its spec is changed.
+func resourceKeys(res map[string][]*crd.NightshiftSchedule) map[string]bool {
+	keys := map[string]bool{}
+	for _, scheds := range res {
+		for _, sched := range scheds {
+			keys[sched.Key()] = true
+		}
+	}
+	return keys
+}
+
+// getResources will return the NightshiftSchedule resources that were found
+// during the last poll, in the order of priority.
+func getResources() []*crd.NightshiftSchedule {
+	resmu.Lock()
+	defer resmu.Unlock()
+	res := []*crd.NightshiftSchedule{}
+	for _, scheds := range resources {
+		res = append(res, scheds...)
+	}
+	crd.Sort(res)
+	return res
+}
+
+// addResourceScanners will add scanners for the NightshiftSchedule resources
+// to the provided agent, starting at the given priority, so they take
+// precedence over the scanners of the configuration file. If status is true,
+// the outcome of the validation is written to the status of each resource.
+// It will return the errors of the scanners that could not be added.
+func addResourceScanners(agent registry, cfg *config.Config, prio int, status bool) []error {
+	errs := []error{}
+	ids := []string{}
+	for _, trgr := range cfg.Trigger {
+		ids = append(ids, trgr.Id)
+	}
+	for _, sched := range getResources() {
+		st := crd.Status{Valid: true, ObservedGeneration: sched.Generation}
+		scfg, err := sched.GetScannerConfig(ids, prio)
+		if err != nil {
+			glog.Errorf("Invalid NightshiftSchedule %s/%s: %s", sched.Namespace, sched.Name, err)
+			st.Valid = false
+			st.Message = err.Error()
+		} else {
+			glog.V(5).Infof("Adding NightshiftSchedule scanner: %s/%s", sched.Namespace, sched.Name)
+			errs = appendError(errs, addScanner(agent, scfg))
+			prio++
+		}
+		if !status {
+			continue
+		}
+		if err := crd.SetStatus(sched, st); err != nil {
+			glog.Errorf("Error updating status of NightshiftSchedule %s/%s: %s", sched.Namespace, sched.Name, err)
+		}
+	}
+	return errs
+}
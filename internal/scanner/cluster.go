@@ -0,0 +1,113 @@
+package scanner
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Cluster describes how to connect to a named cluster. If Kubeconfig is
+// empty, the default kubeconfig is used. If Context is empty, the current
+// context of the kubeconfig is used.
+type Cluster struct {
+	Name       string `json:"name"`
+	Kubeconfig string `json:"kubeconfig"`
+	Context    string `json:"context"`
+}
+
+var (
+	clusters = map[string]Cluster{}
+	configs  = map[string]*rest.Config{}
+	clmu     sync.Mutex
+)
+
+// SetClusters will replace the named clusters that can be referred to by
+// scanner configurations.
+func SetClusters(cls []Cluster) {
+	clmu.Lock()
+	defer clmu.Unlock()
+	clusters = map[string]Cluster{}
+	configs = map[string]*rest.Config{}
+	for _, cl := range cls {
+		clusters[cl.Name] = cl
+	}
+}
+
+// GetClusters will return the configured named clusters.
+func GetClusters() []Cluster {
+	clmu.Lock()
+	defer clmu.Unlock()
+	res := []Cluster{}
+	for _, cl := range clusters {
+		res = append(res, cl)
+	}
+	return res
+}
+
+// GetKubernetes will return the rest config of the cluster with given name,
+// or of the default cluster if no name is given.
+func GetKubernetes(name string) (*rest.Config, error) {
+	return getKubernetes(name)
+}
+
+// NewRESTClient will return a rest client for given api group version, with
+// given api path (e.g. /api or /apis), on the cluster of the given config.
+func NewRESTClient(kubernetes *rest.Config, gv schema.GroupVersion, path string) (*rest.RESTClient, error) {
+	config := *kubernetes
+	config.GroupVersion = &gv
+	config.APIPath = path
+	config.NegotiatedSerializer = serializer.DirectCodecFactory{CodecFactory: scheme.Codecs}
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+	return rest.RESTClientFor(&config)
+}
+
+// getKubernetes will return a kubernetes config object for given cluster. If
+// no cluster name is given, the default cluster is used.
+func getKubernetes(name string) (*rest.Config, error) {
+	if name == "" {
+		return getDefaultKubernetes()
+	}
+	clmu.Lock()
+	defer clmu.Unlock()
+	if config, ok := configs[name]; ok {
+		return config, nil
+	}
+	cl, ok := clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown cluster: %s", name)
+	}
+	kubeconfig := cl.Kubeconfig
+	if kubeconfig == "" {
+		kubeconfig = viper.GetString("openshift.kubeconfig")
+	}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfig},
+		&clientcmd.ConfigOverrides{CurrentContext: cl.Context},
+	).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed loading cluster %s: %s", name, err)
+	}
+	configs[name] = config
+	return config, nil
+}
+
+// getDefaultKubernetes will return a kubernetes config object for the
+// default cluster.
+func getDefaultKubernetes() (*rest.Config, error) {
+	kubeconfig := viper.GetString("openshift.kubeconfig")
+	if kubeconfig != "" {
+		config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+		if err == nil {
+			return config, nil
+		}
+	}
+	return rest.InClusterConfig()
+}
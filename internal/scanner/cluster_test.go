@@ -0,0 +1,81 @@
+package scanner
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:8443
+- name: test
+  cluster:
+    server: https://test.example.com:8443
+users:
+- name: nightshift
+  user:
+    token: secret
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: nightshift
+- name: test
+  context:
+    cluster: test
+    user: nightshift
+current-context: dev
+`
+
+func TestGetKubernetes(t *testing.T) {
+	f, err := ioutil.TempFile("", "kubeconfig")
+	if err != nil {
+		t.Fatalf("failed test - unable to create kubeconfig: %s", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(testKubeconfig)
+	f.Close()
+
+	SetClusters([]Cluster{
+		{Name: "dev", Kubeconfig: f.Name()},
+		{Name: "test", Kubeconfig: f.Name(), Context: "test"},
+		{Name: "broken", Kubeconfig: "/non/existing/kubeconfig"},
+	})
+	defer SetClusters(nil)
+
+	tests := []struct {
+		cluster string
+		host    string
+		err     bool
+	}{
+		{cluster: "dev", host: "https://dev.example.com:8443"},
+		{cluster: "test", host: "https://test.example.com:8443"},
+		{cluster: "broken", err: true},
+		{cluster: "acceptance", err: true},
+	}
+	for i, tst := range tests {
+		cfg, err := getKubernetes(tst.cluster)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error result: %v", i, err)
+			continue
+		}
+		if err == nil && cfg.Host != tst.host {
+			t.Errorf("failed test %d - expected host %s, got %s", i, tst.host, cfg.Host)
+		}
+	}
+
+	if n := len(GetClusters()); n != 3 {
+		t.Errorf("failed test - expected 3 clusters, got %d", n)
+	}
+}
+
+func TestNewForConfigCluster(t *testing.T) {
+	SetClusters(nil)
+	if _, err := NewForConfig(Config{Type: "statefulset", Cluster: "acceptance"}); err == nil {
+		t.Errorf("failed test - expected error for unknown cluster, but got none")
+	}
+}
@@ -0,0 +1,231 @@
+package scanner
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// EventReasonScaled is the reason of the event that is recorded when an
+	// object has been scaled.
+	EventReasonScaled string = "NightshiftScaled"
+	// EventReasonStateSaved is the reason of the event that is recorded when
+	// the state of an object has been saved.
+	EventReasonStateSaved string = "NightshiftStateSaved"
+	// EventReasonScaleFailed is the reason of the event that is recorded when
+	// scaling, or saving the state, of an object failed.
+	EventReasonScaleFailed string = "NightshiftScaleFailed"
+)
+
+// EventRecorder records events on the objects that are handled by nightshift.
+type EventRecorder interface {
+	Event(obj *Object, eventtype, reason, message string)
+}
+
+// maxQueuedEvents is the number of events that can be queued per cluster;
+// events that are recorded while the queue is full are dropped.
+const maxQueuedEvents = 1000
+
+// eventAggregateWindow is the period in which events with the same reason on
+// the same object are aggregated into a single event, by increasing its count.
+const eventAggregateWindow = 10 * time.Minute
+
+// kubeRecorder is the EventRecorder that records kubernetes core/v1 events.
+// Events are created by a single worker per cluster, which aggregates events
+// with the same reason on the same object, as the kubernetes event recorder
+// does.
+type kubeRecorder struct {
+	m         sync.Mutex
+	sinks     map[string]*eventSink
+	newClient func(cluster string) (*rest.RESTClient, error)
+}
+
+// eventSink is the queue of events of a single cluster, together with the
+// rest client and the events that have been created in that cluster.
+type eventSink struct {
+	cluster   string
+	queue     chan *corev1.Event
+	core      *rest.RESTClient
+	newClient func(cluster string) (*rest.RESTClient, error)
+	events    map[eventKey]*corev1.Event
+}
+
+// eventKey is the key on which events are aggregated.
+type eventKey struct {
+	object corev1.ObjectReference
+	reason string
+}
+
+var recorder EventRecorder = newKubeRecorder()
+
+// newKubeRecorder will instantiate a new kubeRecorder object.
+func newKubeRecorder() *kubeRecorder {
+	return &kubeRecorder{
+		sinks:     map[string]*eventSink{},
+		newClient: newEventClient,
+	}
+}
+
+// newEventClient will return a core/v1 rest client for given cluster.
+func newEventClient(cluster string) (*rest.RESTClient, error) {
+	kubernetes, err := getKubernetes(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return NewRESTClient(kubernetes, corev1.SchemeGroupVersion, "/api")
+}
+
+// SetEventRecorder will set the recorder that is used to record events on
+// objects.
+func SetEventRecorder(rec EventRecorder) {
+	recorder = rec
+}
+
+// recordEvent will record an event on given object, if recording events has
+// been enabled.
+func recordEvent(obj *Object, eventtype, reason, format string, args ...interface{}) {
+	if !viper.GetBool("openshift.events") {
+		return
+	}
+	recorder.Event(obj, eventtype, reason, fmt.Sprintf(format, args...))
+}
+
+// Event will queue a kubernetes event for given object, which will be
+// created asynchronously by the worker of the cluster of the object. If the
+// queue is full, the event is dropped.
+func (r *kubeRecorder) Event(obj *Object, eventtype, reason, message string) {
+	ev := newEvent(obj, eventtype, reason, message, time.Now())
+	select {
+	case r.getSink(obj.Cluster).queue <- ev:
+	default:
+		glog.Errorf("Error recording event %s on %s/%s: queue is full", reason, obj.Namespace, obj.Name)
+	}
+}
+
+// getSink will return the event sink for given cluster, and start its worker
+// if it did not exist yet.
+func (r *kubeRecorder) getSink(cluster string) *eventSink {
+	r.m.Lock()
+	defer r.m.Unlock()
+	sink, ok := r.sinks[cluster]
+	if !ok {
+		sink = &eventSink{
+			cluster:   cluster,
+			queue:     make(chan *corev1.Event, maxQueuedEvents),
+			newClient: r.newClient,
+			events:    map[eventKey]*corev1.Event{},
+		}
+		r.sinks[cluster] = sink
+		go sink.run()
+	}
+	return sink
+}
+
+// run will record the queued events.
+func (s *eventSink) run() {
+	for ev := range s.queue {
+		if err := s.record(ev); err != nil {
+			glog.Errorf("Error recording event %s on %s/%s: %s", ev.Reason, ev.Namespace, ev.InvolvedObject.Name, err)
+		}
+	}
+}
+
+// record will create the given event. If an event with the same reason has
+// been recorded on the same object within the aggregate window, that event
+// is updated instead, by increasing its count.
+func (s *eventSink) record(ev *corev1.Event) error {
+	if s.core == nil {
+		core, err := s.newClient(s.cluster)
+		if err != nil {
+			return err
+		}
+		s.core = core
+	}
+	s.prune(ev.LastTimestamp.Time)
+	key := eventKey{object: ev.InvolvedObject, reason: ev.Reason}
+	if prev, ok := s.events[key]; ok {
+		res, err := s.patch(prev, ev)
+		if err == nil {
+			s.events[key] = res
+			return nil
+		}
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		delete(s.events, key)
+	}
+	res := &corev1.Event{}
+	err := s.core.Post().
+		Namespace(ev.Namespace).
+		Resource("events").
+		Body(ev).
+		Do().
+		Into(res)
+	if err != nil {
+		return err
+	}
+	s.events[key] = res
+	return nil
+}
+
+// patch will update the given previously recorded event with the message and
+// timestamp of the given new event, and increase its count.
+func (s *eventSink) patch(prev, ev *corev1.Event) (*corev1.Event, error) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"count":         prev.Count + 1,
+		"lastTimestamp": ev.LastTimestamp,
+		"message":       ev.Message,
+		"type":          ev.Type,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := &corev1.Event{}
+	err = s.core.Patch(types.StrategicMergePatchType).
+		Namespace(prev.Namespace).
+		Resource("events").
+		Name(prev.Name).
+		Body(patch).
+		Do().
+		Into(res)
+	return res, err
+}
+
+// prune will forget the recorded events that were last seen before the
+// aggregate window, so they are no longer aggregated.
+func (s *eventSink) prune(now time.Time) {
+	for key, ev := range s.events {
+		if now.Sub(ev.LastTimestamp.Time) > eventAggregateWindow {
+			delete(s.events, key)
+		}
+	}
+}
+
+// newEvent will return a core/v1 Event object for given object.
+func newEvent(obj *Object, eventtype, reason, message string, now time.Time) *corev1.Event {
+	ts := metav1.NewTime(now)
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", obj.Name, now.UnixNano()),
+			Namespace: obj.Namespace,
+		},
+		InvolvedObject: obj.Reference(),
+		Reason:         reason,
+		Message:        message,
+		Type:           eventtype,
+		FirstTimestamp: ts,
+		LastTimestamp:  ts,
+		Count:          1,
+		Source:         corev1.EventSource{Component: "nightshift"},
+	}
+}
@@ -0,0 +1,216 @@
+package scanner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+type mockRecorder struct {
+	events []string
+	types  []string
+}
+
+func (r *mockRecorder) Event(obj *Object, eventtype, reason, message string) {
+	r.events = append(r.events, reason)
+	r.types = append(r.types, eventtype)
+}
+
+func TestRecordEvent(t *testing.T) {
+	rec := &mockRecorder{}
+	SetEventRecorder(rec)
+	defer SetEventRecorder(newKubeRecorder())
+	defer viper.Set("openshift.events", false)
+
+	state := &mock{}
+	RegisterModule("mock", getFactory("mock", state))
+	obj := &Object{Type: "mock", Replicas: 2}
+
+	viper.Set("openshift.events", false)
+	obj.Scale(1)
+	if len(rec.events) != 0 {
+		t.Errorf("failed test - expected no events when disabled, got %v", rec.events)
+	}
+
+	viper.Set("openshift.events", true)
+	tests := []struct {
+		fn    func() error
+		err   error
+		event string
+		typ   string
+	}{
+		{fn: func() error { return obj.Scale(0) }, event: EventReasonScaled, typ: corev1.EventTypeNormal},
+		{fn: func() error { return obj.Scale(1) }, err: errors.New("err"), event: EventReasonScaleFailed, typ: corev1.EventTypeWarning},
+		{fn: obj.SaveState, event: EventReasonStateSaved, typ: corev1.EventTypeNormal},
+	}
+	for i, tst := range tests {
+		rec.events = nil
+		rec.types = nil
+		state.err = tst.err
+		tst.fn()
+		if len(rec.events) != 1 {
+			t.Errorf("failed test %d - expected 1 event, got %v", i, rec.events)
+			continue
+		}
+		if rec.events[0] != tst.event || rec.types[0] != tst.typ {
+			t.Errorf("failed test %d - expected %s/%s, got %s/%s", i, tst.typ, tst.event, rec.types[0], rec.events[0])
+		}
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	obj := &Object{
+		Namespace:  "dev",
+		Name:       "app",
+		UID:        "1234",
+		Kind:       "DeploymentConfig",
+		APIVersion: "apps.openshift.io/v1",
+	}
+	now := time.Now()
+	ev := newEvent(obj, corev1.EventTypeNormal, EventReasonScaled, "msg", now)
+	if ev.Namespace != "dev" {
+		t.Errorf("failed test - expected namespace dev, got %s", ev.Namespace)
+	}
+	ref := ev.InvolvedObject
+	if ref.Kind != "DeploymentConfig" || ref.APIVersion != "apps.openshift.io/v1" ||
+		ref.Name != "app" || ref.Namespace != "dev" || string(ref.UID) != "1234" {
+		t.Errorf("failed test - invalid involved object %#v", ref)
+	}
+	if ev.Reason != EventReasonScaled || ev.Message != "msg" || ev.Type != corev1.EventTypeNormal {
+		t.Errorf("failed test - invalid event %#v", ev)
+	}
+	if ev.Source.Component != "nightshift" || ev.Count != 1 {
+		t.Errorf("failed test - invalid event source or count %#v", ev)
+	}
+}
+
+// fakeEvents is a minimal kubernetes api that supports creating and patching
+// events.
+type fakeEvents struct {
+	m       sync.Mutex
+	events  map[string]*corev1.Event
+	posts   int
+	patches int
+	done    chan bool
+}
+
+func (f *fakeEvents) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	defer func() {
+		if f.done != nil {
+			f.done <- true
+		}
+	}()
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/dev/events")
+	switch {
+	case r.Method == "POST" && path == "":
+		ev := &corev1.Event{}
+		json.NewDecoder(r.Body).Decode(ev)
+		f.posts++
+		f.events[ev.Name] = ev
+		json.NewEncoder(w).Encode(ev)
+	case r.Method == "PATCH" && f.events[strings.TrimPrefix(path, "/")] != nil:
+		ev := f.events[strings.TrimPrefix(path, "/")]
+		json.NewDecoder(r.Body).Decode(ev)
+		f.patches++
+		json.NewEncoder(w).Encode(ev)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`)
+	}
+}
+
+func TestEventSink(t *testing.T) {
+	api := &fakeEvents{events: map[string]*corev1.Event{}}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	clients := 0
+	sink := &eventSink{
+		newClient: func(cluster string) (*rest.RESTClient, error) {
+			clients++
+			return NewRESTClient(&rest.Config{Host: srv.URL}, corev1.SchemeGroupVersion, "/api")
+		},
+		events: map[eventKey]*corev1.Event{},
+	}
+	app := &Object{Namespace: "dev", Name: "app", UID: "app"}
+	db := &Object{Namespace: "dev", Name: "db", UID: "db"}
+	now := time.Now()
+
+	tests := []struct {
+		obj     *Object
+		reason  string
+		at      time.Time
+		posts   int
+		patches int
+		count   int32
+	}{
+		{obj: app, reason: EventReasonScaleFailed, at: now, posts: 1, patches: 0, count: 1},
+		{obj: app, reason: EventReasonScaleFailed, at: now.Add(time.Second), posts: 1, patches: 1, count: 2},
+		{obj: app, reason: EventReasonScaleFailed, at: now.Add(2 * time.Second), posts: 1, patches: 2, count: 3},
+		{obj: app, reason: EventReasonScaled, at: now.Add(3 * time.Second), posts: 2, patches: 2, count: 1},
+		{obj: db, reason: EventReasonScaleFailed, at: now.Add(4 * time.Second), posts: 3, patches: 2, count: 1},
+		{obj: app, reason: EventReasonScaleFailed, at: now.Add(time.Hour), posts: 4, patches: 2, count: 1},
+	}
+	for i, tst := range tests {
+		ev := newEvent(tst.obj, corev1.EventTypeWarning, tst.reason, "msg", tst.at)
+		if err := sink.record(ev); err != nil {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		res := sink.events[eventKey{object: ev.InvolvedObject, reason: tst.reason}]
+		if api.posts != tst.posts || api.patches != tst.patches || res == nil || res.Count != tst.count {
+			t.Errorf("failed test %d - expected %d posts, %d patches and count %d, got %d, %d and %v", i, tst.posts, tst.patches, tst.count, api.posts, api.patches, res)
+		}
+	}
+	if clients != 1 {
+		t.Errorf("failed test - expected the client to be cached, got %d clients", clients)
+	}
+
+	// events that no longer exist are recreated
+	api.events = map[string]*corev1.Event{}
+	if err := sink.record(newEvent(db, corev1.EventTypeWarning, EventReasonScaleFailed, "msg", now.Add(5*time.Second))); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if api.posts != 5 {
+		t.Errorf("failed test - expected removed event to be recreated, got %d posts", api.posts)
+	}
+}
+
+func TestKubeRecorder(t *testing.T) {
+	api := &fakeEvents{events: map[string]*corev1.Event{}, done: make(chan bool, 10)}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	rec := newKubeRecorder()
+	rec.newClient = func(cluster string) (*rest.RESTClient, error) {
+		return NewRESTClient(&rest.Config{Host: srv.URL}, corev1.SchemeGroupVersion, "/api")
+	}
+	obj := &Object{Namespace: "dev", Name: "app", UID: "app"}
+	for i := 0; i < 3; i++ {
+		rec.Event(obj, corev1.EventTypeWarning, EventReasonScaleFailed, "msg")
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-api.done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("failed test - expected 3 requests, got %d", i)
+		}
+	}
+	api.m.Lock()
+	defer api.m.Unlock()
+	if api.posts != 1 || api.patches != 2 || len(rec.sinks) != 1 {
+		t.Errorf("failed test - expected 1 post and 2 patches by 1 worker, got %d posts and %d patches by %d", api.posts, api.patches, len(rec.sinks))
+	}
+}
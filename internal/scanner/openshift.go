@@ -2,11 +2,13 @@ package scanner
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/golang/glog"
 	v1 "github.com/openshift/api/apps/v1"
 	appsv1 "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
 )
@@ -24,13 +26,18 @@ func init() {
 
 // NewOpenShiftScanner will instantiate a new OpenShiftScanner object.
 func NewOpenShiftScanner() (Scanner, error) {
-	kubernetes, err := getKubernetes()
+	return &OpenShiftScanner{}, nil
+}
+
+// connect will instantiate the k8s client config for the cluster that is
+// configured for this scanner.
+func (s *OpenShiftScanner) connect() error {
+	kubernetes, err := getKubernetes(s.config.Cluster)
 	if err != nil {
-		return nil, fmt.Errorf("failed instantiating k8s client: %s", err)
+		return fmt.Errorf("failed instantiating k8s client: %s", err)
 	}
-	return &OpenShiftScanner{
-		kubernetes: kubernetes,
-	}, nil
+	s.kubernetes = kubernetes
+	return nil
 }
 
 // SetConfig will set the generic configuration for this scanner.
@@ -53,36 +60,67 @@ func (s *OpenShiftScanner) GetObjects() ([]*Object, error) {
 	return s.getObjects(rcs)
 }
 
-// Scale will scale a given object to given amount of replicas.
+// Scale will scale a given object to given amount of replicas. It will use
+// the scale subresource, and retry if the scale was modified concurrently.
 func (s *OpenShiftScanner) Scale(obj *Object, replicas int) error {
 	glog.Infof("Scaling %s/%s to %d replicas", obj.Namespace, obj.Name, replicas)
 	apps, err := appsv1.NewForConfig(s.kubernetes)
 	if err != nil {
 		return err
 	}
-	scale, err := apps.DeploymentConfigs(obj.Namespace).GetScale(obj.Name, metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("GetScale failed with: %s", err)
-	}
-	scale.Spec.Replicas = int32(replicas)
-	_, err = apps.DeploymentConfigs(obj.Namespace).UpdateScale(obj.Name, scale)
-	return err
+	return retry(func() error {
+		scale, err := apps.DeploymentConfigs(obj.Namespace).GetScale(obj.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		scale.Spec.Replicas = int32(replicas)
+		_, err = apps.DeploymentConfigs(obj.Namespace).UpdateScale(obj.Name, scale)
+		return err
+	})
 }
 
 // SaveState will save the current number of replicas as an annotation on the
-// deployment config.
+// deployment config. The annotation is written with a merge patch, and will
+// be retried if the deployment config was modified concurrently.
 func (s *OpenShiftScanner) SaveState(obj *Object) (int, error) {
-	dc, err := s.getDeploymentConfig(obj)
+	apps, err := appsv1.NewForConfig(s.kubernetes)
 	if err != nil {
 		return 0, err
 	}
-	repl := int(dc.Spec.Replicas)
-	dc.ObjectMeta = updateState(dc.ObjectMeta, repl)
-	apps, _ := appsv1.NewForConfig(s.kubernetes)
-	_, err = apps.DeploymentConfigs(obj.Namespace).Update(dc)
+	repl := 0
+	err = retry(func() error {
+		dc, err := s.getDeploymentConfig(obj)
+		if err != nil {
+			return err
+		}
+		repl = int(dc.Spec.Replicas)
+		patch, err := statePatch(dc.ResourceVersion, repl)
+		if err != nil {
+			return err
+		}
+		_, err = apps.DeploymentConfigs(obj.Namespace).Patch(obj.Name, types.MergePatchType, patch)
+		return err
+	})
 	return repl, err
 }
 
+// Snooze will store the time until scheduled events should be suppressed as
+// an annotation on the deployment config.
+func (s *OpenShiftScanner) Snooze(obj *Object, until *time.Time) error {
+	apps, err := appsv1.NewForConfig(s.kubernetes)
+	if err != nil {
+		return err
+	}
+	patch, err := snoozePatch(until)
+	if err != nil {
+		return err
+	}
+	return retry(func() error {
+		_, err := apps.DeploymentConfigs(obj.Namespace).Patch(obj.Name, types.MergePatchType, patch)
+		return err
+	})
+}
+
 // getDeploymentConfig will return an DeploymentConfig object.
 func (s *OpenShiftScanner) getDeploymentConfig(obj *Object) (*v1.DeploymentConfig, error) {
 	apps, err := appsv1.NewForConfig(s.kubernetes)
@@ -144,6 +182,8 @@ func (s *OpenShiftScanner) unmarshall(kobj interface{}) (*Object, error) {
 		return nil, fmt.Errorf("can't unmarshall %v to DeploymentConfig", m)
 	}
 	obj := NewObjectForScanner(s)
+	obj.Kind = "DeploymentConfig"
+	obj.APIVersion = "apps.openshift.io/v1"
 	if err := obj.updateWithMeta(m.ObjectMeta); err != nil {
 		glog.Error(err)
 	}
@@ -3,10 +3,13 @@ package scanner
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/joyrex2001/nightshift/internal/schedule"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // Scanner is the public interface of a scanner object.
@@ -15,6 +18,7 @@ type Scanner interface {
 	GetConfig() Config
 	GetObjects() ([]*Object, error)
 	SaveState(*Object) (int, error)
+	Snooze(*Object, *time.Time) error
 	Scale(*Object, int) error
 	Watch(chan bool) (chan Event, error)
 }
@@ -22,6 +26,12 @@ type Scanner interface {
 // Factory is the factory method for a scanner implementation module.
 type Factory func() (Scanner, error)
 
+// clusterScanner is implemented by scanners that need to connect to the
+// cluster that is configured in their Config.
+type clusterScanner interface {
+	connect() error
+}
+
 // Config describes the configuration of a scanner. It includes ScannerType
 // to allow to be used by the factory NewForConfig method.
 type Config struct {
@@ -31,20 +41,26 @@ type Config struct {
 	Schedule  []*schedule.Schedule `json:"schedule"`
 	Type      string               `json:"type"`
 	Priority  int                  `json:"priority"`
+	Cluster   string               `json:"cluster"`
 }
 
 // Object is an object found by the scanner.
 type Object struct {
-	Namespace string               `json:"namespace"`
-	UID       string               `json:"uid"`
-	Name      string               `json:"name"`
-	Type      string               `json:"type"`
-	Schedule  []*schedule.Schedule `json:"schedule"`
-	State     *State               `json:"state"`
-	Replicas  int                  `json:"replicas"`
-	Priority  int                  `json:"priority"`
-	ScannerId string               `json:"scanner_id"`
-	scanner   Scanner
+	Cluster     string               `json:"cluster"`
+	Namespace   string               `json:"namespace"`
+	UID         string               `json:"uid"`
+	Name        string               `json:"name"`
+	Type        string               `json:"type"`
+	Kind        string               `json:"kind"`
+	APIVersion  string               `json:"api_version"`
+	Schedule    []*schedule.Schedule `json:"schedule"`
+	State       *State               `json:"state"`
+	Replicas    int                  `json:"replicas"`
+	Priority    int                  `json:"priority"`
+	ScannerId   string               `json:"scanner_id"`
+	SnoozeUntil *time.Time           `json:"snooze_until"`
+	Override    string               `json:"override"`
+	scanner     Scanner
 }
 
 // State defines a state of the object.
@@ -97,6 +113,11 @@ func NewForConfig(cfg Config) (Scanner, error) {
 		return nil, err
 	}
 	scnr.SetConfig(cfg)
+	if conn, ok := scnr.(clusterScanner); ok {
+		if err := conn.connect(); err != nil {
+			return nil, err
+		}
+	}
 	return scnr, nil
 }
 
@@ -105,6 +126,7 @@ func NewForConfig(cfg Config) (Scanner, error) {
 func NewObjectForScanner(scnr Scanner) *Object {
 	cfg := scnr.GetConfig()
 	return &Object{
+		Cluster:   cfg.Cluster,
 		Namespace: cfg.Namespace,
 		Priority:  cfg.Priority,
 		Type:      cfg.Type,
@@ -122,6 +144,10 @@ func (obj *Object) Copy() *Object {
 		new.State = &State{}
 		*(new.State) = *(obj.State)
 	}
+	if new.SnoozeUntil != nil {
+		until := *(obj.SnoozeUntil)
+		new.SnoozeUntil = &until
+	}
 	new.Schedule = []*schedule.Schedule{}
 	for _, sched := range obj.Schedule {
 		new.Schedule = append(new.Schedule, sched.Copy())
@@ -139,18 +165,38 @@ func (obj *Object) updateWithMeta(meta metav1.ObjectMeta) error {
 	if err != nil {
 		return fmt.Errorf("error parsing schedule annotation for %s (%s); %s", meta.UID, meta.Name, err)
 	}
+	obj.Override = getOverride(meta.Annotations)
 	obj.State, err = getState(meta.Annotations)
 	if err != nil {
 		return fmt.Errorf("error parsing state annotation for %s (%s); %s", meta.UID, meta.Name, err)
 	}
+	obj.SnoozeUntil, err = getSnooze(meta.Annotations)
+	if err != nil {
+		return fmt.Errorf("error parsing snooze annotation for %s (%s); %s", meta.UID, meta.Name, err)
+	}
 	return nil
 }
 
-// getScanner will lazy load the appropriate scanner object for this resource.
+// GetScannerConfig will return the configuration of the scanner that found
+// this object.
+func (obj *Object) GetScannerConfig() (Config, error) {
+	scanner, err := obj.getScanner()
+	if err != nil {
+		return Config{}, err
+	}
+	return scanner.GetConfig(), nil
+}
+
+// getScanner will lazy load the appropriate scanner object for this resource,
+// connected to the cluster of this resource.
 func (obj *Object) getScanner() (Scanner, error) {
 	var err error
 	if obj.scanner == nil {
-		obj.scanner, err = New(obj.Type)
+		obj.scanner, err = NewForConfig(Config{
+			Type:      obj.Type,
+			Cluster:   obj.Cluster,
+			Namespace: obj.Namespace,
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -165,8 +211,10 @@ func (obj *Object) Scale(replicas int) error {
 		return err
 	}
 	if err := scanner.Scale(obj, replicas); err != nil {
+		recordEvent(obj, corev1.EventTypeWarning, EventReasonScaleFailed, "Scaling to %d replicas failed: %s", replicas, err)
 		return err
 	}
+	recordEvent(obj, corev1.EventTypeNormal, EventReasonScaled, "Scaled from %d to %d replicas", obj.Replicas, replicas)
 	obj.Replicas = replicas
 	return nil
 }
@@ -178,8 +226,41 @@ func (obj *Object) SaveState() error {
 		return err
 	}
 	repl, err := scanner.SaveState(obj)
-	if err == nil {
-		obj.State = &State{Replicas: repl}
+	if err != nil {
+		recordEvent(obj, corev1.EventTypeWarning, EventReasonScaleFailed, "Saving state failed: %s", err)
+		return err
 	}
-	return err
+	recordEvent(obj, corev1.EventTypeNormal, EventReasonStateSaved, "Saved state of %d replicas", repl)
+	obj.State = &State{Replicas: repl}
+	return nil
+}
+
+// Reference will return a kubernetes object reference for this object.
+func (obj *Object) Reference() corev1.ObjectReference {
+	return corev1.ObjectReference{
+		Kind:       obj.Kind,
+		APIVersion: obj.APIVersion,
+		Namespace:  obj.Namespace,
+		Name:       obj.Name,
+		UID:        types.UID(obj.UID),
+	}
+}
+
+// Snooze will suppress scheduled events for this object until the given
+// time. If nil is provided, an existing snooze will be removed.
+func (obj *Object) Snooze(until *time.Time) error {
+	scanner, err := obj.getScanner()
+	if err != nil {
+		return err
+	}
+	if err := scanner.Snooze(obj, until); err != nil {
+		return err
+	}
+	obj.SnoozeUntil = until
+	return nil
+}
+
+// IsSnoozed will return true if the object is snoozed at the given time.
+func (obj *Object) IsSnoozed(at time.Time) bool {
+	return obj.SnoozeUntil != nil && at.Before(*obj.SnoozeUntil)
 }
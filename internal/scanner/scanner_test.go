@@ -1,9 +1,11 @@
 package scanner
 
 import (
+	"encoding/json"
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -16,6 +18,7 @@ type mock struct {
 	state    *Object
 	scale    *Object
 	replicas int
+	snooze   *time.Time
 	err      error
 }
 
@@ -36,6 +39,11 @@ func (m *mock) SaveState(obj *Object) (int, error) {
 	return 0, nil
 }
 
+func (m *mock) Snooze(obj *Object, until *time.Time) error {
+	m.snooze = until
+	return m.err
+}
+
 func (m *mock) Scale(obj *Object, r int) error {
 	m.scale = obj
 	m.replicas = r
@@ -140,6 +148,52 @@ func TestScale(t *testing.T) {
 	}
 }
 
+// clusterMock is a mock scanner that needs to connect to its cluster.
+type clusterMock struct {
+	mock
+	connected string
+}
+
+func (m *clusterMock) connect() error {
+	if m.cfg.Cluster == "unknown" {
+		return errors.New("unknown cluster")
+	}
+	m.connected = m.cfg.Cluster
+	return nil
+}
+
+func TestScaleDecoded(t *testing.T) {
+	state := &clusterMock{}
+	RegisterModule("clustermock", func() (Scanner, error) { return state, nil })
+	tests := []struct {
+		in  string
+		err bool
+	}{
+		{in: `{"type":"clustermock","cluster":"test","namespace":"dev","name":"app"}`},
+		{in: `{"type":"clustermock","cluster":"unknown","namespace":"dev","name":"app"}`, err: true},
+	}
+	for i, tst := range tests {
+		state.connected = ""
+		obj := &Object{}
+		if err := json.Unmarshal([]byte(tst.in), obj); err != nil {
+			t.Fatalf("failed test %d - unexpected err: %s", i, err)
+		}
+		err := obj.Scale(2)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if tst.err {
+			continue
+		}
+		if state.connected != "test" || state.cfg.Namespace != "dev" || state.replicas != 2 {
+			t.Errorf("failed test %d - expected scanner connected to test, got %v", i, state)
+		}
+	}
+}
+
 func TestSaveState(t *testing.T) {
 	state := &mock{}
 	RegisterModule("mock", getFactory("mock", state))
@@ -160,6 +214,40 @@ func TestSaveState(t *testing.T) {
 	}
 }
 
+func TestSnooze(t *testing.T) {
+	state := &mock{}
+	RegisterModule("mock", getFactory("mock", state))
+	now := time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)
+	until := now.Add(2 * time.Hour)
+	obj := &Object{Type: "mock"}
+	state.err = nil
+	if err := obj.Snooze(&until); err != nil {
+		t.Errorf("failed test - snooze unexpected err: %s", err)
+	}
+	if state.snooze != &until || obj.SnoozeUntil != &until {
+		t.Errorf("failed test - snooze not applied")
+	}
+	if !obj.IsSnoozed(now) {
+		t.Errorf("failed test - expected object to be snoozed at %s", now)
+	}
+	if obj.IsSnoozed(until) {
+		t.Errorf("failed test - expected object not to be snoozed at %s", until)
+	}
+	if err := obj.Snooze(nil); err != nil {
+		t.Errorf("failed test - snooze unexpected err: %s", err)
+	}
+	if obj.IsSnoozed(now) {
+		t.Errorf("failed test - expected snooze to be removed")
+	}
+	state.err = errors.New("some error")
+	if err := obj.Snooze(&until); err == nil {
+		t.Errorf("failed test - expected an error, but got none")
+	}
+	if obj.SnoozeUntil != nil {
+		t.Errorf("failed test - snooze applied while scanner failed")
+	}
+}
+
 func TestNewObjectForScanner(t *testing.T) {
 	scnr := &mock{typ: "mock"}
 	sched := []*schedule.Schedule{{}, {}}
@@ -200,6 +288,7 @@ func TestCopy(t *testing.T) {
 		{UID: "123", Name: "Something"},
 		{UID: "123", Name: "Something", ScannerId: "somescanner"},
 		{UID: "123", Name: "Something", State: &State{Replicas: 1}},
+		{UID: "123", Name: "Something", SnoozeUntil: &time.Time{}},
 		{UID: "123", Name: "Something", Schedule: []*schedule.Schedule{sched1, sched2}},
 	}
 	for i, obj := range tests {
@@ -218,6 +307,9 @@ func TestCopy(t *testing.T) {
 		if new.ScannerId == obj.ScannerId {
 			t.Errorf("failed test %d - change to ScannerId is copied to new object as well", i)
 		}
+		if new.SnoozeUntil != nil && new.SnoozeUntil == obj.SnoozeUntil {
+			t.Errorf("failed test %d - object SnoozeUntil attribute is identical", i)
+		}
 		if new.State != nil && new.State == obj.State {
 			t.Errorf("failed test %d - object State attribute is identical (%p,%p)", i, new.State, obj.State)
 		}
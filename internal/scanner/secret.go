@@ -0,0 +1,35 @@
+package scanner
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// GetSecret will return the value of given key in the kubernetes secret with
+// given name, in given namespace of given cluster.
+func GetSecret(cluster, namespace, name, key string) (string, error) {
+	kubernetes, err := getKubernetes(cluster)
+	if err != nil {
+		return "", err
+	}
+	core, err := NewRESTClient(kubernetes, corev1.SchemeGroupVersion, "/api")
+	if err != nil {
+		return "", err
+	}
+	secret := &corev1.Secret{}
+	err = core.Get().
+		Namespace(namespace).
+		Resource("secrets").
+		Name(name).
+		Do().
+		Into(secret)
+	if err != nil {
+		return "", err
+	}
+	val, ok := secret.Data[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found in secret %s/%s", key, namespace, name)
+	}
+	return string(val), nil
+}
@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGetSecret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/ops/secrets/ci" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"kind":"Secret","apiVersion":"v1","metadata":{"name":"ci","namespace":"ops"},"data":{"token":"czNjcjN0"}}`))
+	}))
+	defer srv.Close()
+
+	f, err := ioutil.TempFile("", "kubeconfig")
+	if err != nil {
+		t.Fatalf("failed test - unable to create kubeconfig: %s", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(strings.Replace(testKubeconfig, "https://dev.example.com:8443", srv.URL, 1))
+	f.Close()
+
+	SetClusters([]Cluster{{Name: "dev", Kubeconfig: f.Name()}})
+	defer SetClusters(nil)
+
+	tests := []struct {
+		namespace string
+		name      string
+		key       string
+		value     string
+		err       bool
+	}{
+		{namespace: "ops", name: "ci", key: "token", value: "s3cr3t"},
+		{namespace: "ops", name: "ci", key: "password", err: true},
+		{namespace: "ops", name: "other", key: "token", err: true},
+	}
+	for i, tst := range tests {
+		val, err := GetSecret("dev", tst.namespace, tst.name, tst.key)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error result: %v", i, err)
+		}
+		if val != tst.value {
+			t.Errorf("failed test %d - expected %s, got %s", i, tst.value, val)
+		}
+	}
+}
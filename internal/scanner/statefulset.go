@@ -2,10 +2,12 @@ package scanner
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/golang/glog"
 	v1beta "k8s.io/api/apps/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	appsv1beta "k8s.io/client-go/kubernetes/typed/apps/v1beta1"
 	"k8s.io/client-go/rest"
@@ -24,13 +26,18 @@ func init() {
 
 // NewStatefulSetScanner will instantiate a new StatefulSetScanner object.
 func NewStatefulSetScanner() (Scanner, error) {
-	kubernetes, err := getKubernetes()
+	return &StatefulSetScanner{}, nil
+}
+
+// connect will instantiate the k8s client config for the cluster that is
+// configured for this scanner.
+func (s *StatefulSetScanner) connect() error {
+	kubernetes, err := getKubernetes(s.config.Cluster)
 	if err != nil {
-		return nil, fmt.Errorf("failed instantiating k8s client: %s", err)
+		return fmt.Errorf("failed instantiating k8s client: %s", err)
 	}
-	return &StatefulSetScanner{
-		kubernetes: kubernetes,
-	}, nil
+	s.kubernetes = kubernetes
+	return nil
 }
 
 // SetConfig will set the generic configuration for this scanner.
@@ -53,34 +60,80 @@ func (s *StatefulSetScanner) GetObjects() ([]*Object, error) {
 	return s.getObjects(rcs)
 }
 
-// Scale will scale a given object to given amount of replicas.
+// Scale will scale a given object to given amount of replicas. It will use
+// the scale subresource, and retry if the scale was modified concurrently.
 func (s *StatefulSetScanner) Scale(obj *Object, replicas int) error {
 	glog.Infof("Scaling %s/%s to %d replicas", obj.Namespace, obj.Name, replicas)
-	ss, err := s.getStatefulSet(obj)
+	apps, err := appsv1beta.NewForConfig(s.kubernetes)
 	if err != nil {
-		return fmt.Errorf("GetScale failed with: %s", err)
+		return err
 	}
-	repl := int32(replicas)
-	ss.Spec.Replicas = &repl
-	apps, _ := appsv1beta.NewForConfig(s.kubernetes)
-	_, err = apps.StatefulSets(obj.Namespace).Update(ss)
-	return err
+	return retry(func() error {
+		scale := &v1beta.Scale{}
+		err := apps.RESTClient().Get().
+			Namespace(obj.Namespace).
+			Resource("statefulsets").
+			Name(obj.Name).
+			SubResource("scale").
+			Do().
+			Into(scale)
+		if err != nil {
+			return err
+		}
+		scale.Spec.Replicas = int32(replicas)
+		return apps.RESTClient().Put().
+			Namespace(obj.Namespace).
+			Resource("statefulsets").
+			Name(obj.Name).
+			SubResource("scale").
+			Body(scale).
+			Do().
+			Error()
+	})
 }
 
 // SaveState will save the current number of replicas as an annotation on the
-// statefulset config.
+// statefulset. The annotation is written with a merge patch, and will be
+// retried if the statefulset was modified concurrently.
 func (s *StatefulSetScanner) SaveState(obj *Object) (int, error) {
-	ss, err := s.getStatefulSet(obj)
+	apps, err := appsv1beta.NewForConfig(s.kubernetes)
 	if err != nil {
 		return 0, err
 	}
-	repl := int(*ss.Spec.Replicas)
-	ss.ObjectMeta = updateState(ss.ObjectMeta, repl)
-	apps, _ := appsv1beta.NewForConfig(s.kubernetes)
-	_, err = apps.StatefulSets(obj.Namespace).Update(ss)
+	repl := 0
+	err = retry(func() error {
+		ss, err := s.getStatefulSet(obj)
+		if err != nil {
+			return err
+		}
+		repl = int(*ss.Spec.Replicas)
+		patch, err := statePatch(ss.ResourceVersion, repl)
+		if err != nil {
+			return err
+		}
+		_, err = apps.StatefulSets(obj.Namespace).Patch(obj.Name, types.MergePatchType, patch)
+		return err
+	})
 	return repl, err
 }
 
+// Snooze will store the time until scheduled events should be suppressed as
+// an annotation on the statefulset.
+func (s *StatefulSetScanner) Snooze(obj *Object, until *time.Time) error {
+	apps, err := appsv1beta.NewForConfig(s.kubernetes)
+	if err != nil {
+		return err
+	}
+	patch, err := snoozePatch(until)
+	if err != nil {
+		return err
+	}
+	return retry(func() error {
+		_, err := apps.StatefulSets(obj.Namespace).Patch(obj.Name, types.MergePatchType, patch)
+		return err
+	})
+}
+
 // getStatefulSet will return the statefulset for given object.
 func (s *StatefulSetScanner) getStatefulSet(obj *Object) (*v1beta.StatefulSet, error) {
 	apps, err := appsv1beta.NewForConfig(s.kubernetes)
@@ -142,6 +195,8 @@ func (s *StatefulSetScanner) unmarshall(kobj interface{}) (*Object, error) {
 		return nil, fmt.Errorf("can't unmarshall %v to Statefulset", m)
 	}
 	obj := NewObjectForScanner(s)
+	obj.Kind = "StatefulSet"
+	obj.APIVersion = "apps/v1beta1"
 	if err := obj.updateWithMeta(m.ObjectMeta); err != nil {
 		glog.Error(err)
 	}
@@ -1,18 +1,16 @@
 package scanner
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang/glog"
-	"github.com/spf13/viper"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/joyrex2001/nightshift/internal/metrics"
 	"github.com/joyrex2001/nightshift/internal/schedule"
@@ -25,19 +23,28 @@ const (
 	IgnoreAnnotation string = "joyrex2001.com/nightshift.ignore"
 	// SaveStateAnnotation is the annotation used to store the state.
 	SaveStateAnnotation string = "joyrex2001.com/nightshift.savestate"
+	// SnoozeAnnotation is the annotation used to suppress scheduled events
+	// until the given (RFC3339) timestamp.
+	SnoozeAnnotation string = "joyrex2001.com/nightshift.snooze-until"
 )
 
-// getKubernetes will return a kubernetes config object.
-func getKubernetes() (*rest.Config, error) {
-	kubeconfig := viper.GetString("openshift.kubeconfig")
-	if kubeconfig != "" {
-		config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
-		if err == nil {
-			return config, nil
-		}
-	}
-	return rest.InClusterConfig()
-}
+const (
+	// OverrideNone indicates the configured schedule is applied.
+	OverrideNone string = "none"
+	// OverrideSchedule indicates the schedule annotation overrides the
+	// configured schedule.
+	OverrideSchedule string = "schedule"
+	// OverrideIgnore indicates the ignore annotation disabled the schedule.
+	OverrideIgnore string = "ignore"
+)
+
+var (
+	// retryAttempts is the maximum number of attempts for api calls that
+	// fail with a retryable error.
+	retryAttempts = 5
+	// retryBackoff is the initial backoff between retries.
+	retryBackoff = 100 * time.Millisecond
+)
 
 // getState will return a State object based on the value of the State
 // annotation on the deployment config. If no annotation exist, it will return
@@ -55,23 +62,93 @@ func getState(annotations map[string]string) (*State, error) {
 	return &State{Replicas: repl}, nil
 }
 
-// updateState will update a kubernetes ObjectMeta struct by either adding or
-// updating the savestate annotation with the given amount of replicas. It
-// will return the updated struct.
-func updateState(meta metav1.ObjectMeta, repl int) metav1.ObjectMeta {
-	if meta.Annotations == nil {
-		meta.Annotations = map[string]string{}
+// statePatch will return a json merge patch that sets the savestate
+// annotation to the given amount of replicas. The patch includes the given
+// resourceVersion, so it will fail with a conflict if the object has been
+// modified since it was read.
+func statePatch(resourceVersion string, repl int) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": resourceVersion,
+			"annotations": map[string]interface{}{
+				SaveStateAnnotation: strconv.Itoa(repl),
+			},
+		},
+	})
+}
+
+// getSnooze will return the time until scheduled events should be suppressed
+// as set in the snooze annotation. If no annotation exist, it will return nil.
+func getSnooze(annotations map[string]string) (*time.Time, error) {
+	ann, ok := annotations[SnoozeAnnotation]
+	if !ok || ann == "" {
+		return nil, nil
+	}
+	until, err := time.Parse(time.RFC3339, ann)
+	if err != nil {
+		return nil, err
+	}
+	return &until, nil
+}
+
+// snoozePatch will return a json merge patch that either adds, updates or
+// removes (if nil) the snooze annotation.
+func snoozePatch(until *time.Time) ([]byte, error) {
+	var value interface{}
+	if until != nil {
+		value = until.Format(time.RFC3339)
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				SnoozeAnnotation: value,
+			},
+		},
+	})
+}
+
+// retry will execute given function, and will retry it with an exponential
+// backoff if it fails with an error that is considered retryable (e.g.
+// conflicts or server timeouts). Each failure is counted as either a
+// retryable or permanent error in the metrics.
+func retry(fn func() error) error {
+	backoff := retryBackoff
+	var err error
+	for i := 0; i < retryAttempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if !isRetryable(err) {
+			metrics.Increase("api_permanent_error")
+			return err
+		}
+		metrics.Increase("api_retryable_error")
+		glog.V(4).Infof("Retrying after retryable error: %s", err)
+		time.Sleep(backoff)
+		backoff += backoff
 	}
-	meta.Annotations[SaveStateAnnotation] = strconv.Itoa(repl)
-	return meta
+	return err
+}
+
+// isRetryable will return true if the given error is a transient kubernetes
+// api error that is worth retrying.
+func isRetryable(err error) bool {
+	return errors.IsConflict(err) ||
+		errors.IsServerTimeout(err) ||
+		errors.IsTimeout(err) ||
+		errors.IsTooManyRequests(err) ||
+		errors.IsServiceUnavailable(err) ||
+		errors.IsInternalError(err)
 }
 
 // getSchedule will return a list of schedules, taken the annotations and
-// defaults into account.
+// defaults into account. If the object should be ignored, an empty list of
+// schedules is returned, so the object will still be known (and explainable)
+// but will never be scaled.
 func getSchedule(cfgsched []*schedule.Schedule, annotations map[string]string) ([]*schedule.Schedule, error) {
 	dis := strings.ToLower(annotations[IgnoreAnnotation])
 	if dis == "true" {
-		return nil, nil
+		return []*schedule.Schedule{}, nil
 	} else if dis != "false" && dis != "" {
 		return nil, fmt.Errorf("invalid value '%s' for %s", dis, IgnoreAnnotation)
 	}
@@ -81,6 +158,18 @@ func getSchedule(cfgsched []*schedule.Schedule, annotations map[string]string) (
 	return cfgsched, nil
 }
 
+// getOverride will return which annotation, if any, overrides the configured
+// schedule.
+func getOverride(annotations map[string]string) string {
+	if strings.ToLower(annotations[IgnoreAnnotation]) == "true" {
+		return OverrideIgnore
+	}
+	if annotations[ScheduleAnnotation] != "" {
+		return OverrideSchedule
+	}
+	return OverrideNone
+}
+
 // annotationToSchedule will convert the contents of the schedule annotation
 // to an array of Schedule objects. It will produce an error if the provided
 // annotation value is invalid.
@@ -2,12 +2,16 @@ package scanner
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
 
 	"github.com/joyrex2001/nightshift/internal/schedule"
 )
@@ -118,6 +122,42 @@ func TestGetSchedule(t *testing.T) {
 	}
 }
 
+func TestGetOverride(t *testing.T) {
+	tests := []struct {
+		data     map[string]string
+		override string
+	}{
+		{
+			data:     map[string]string{},
+			override: OverrideNone,
+		},
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.schedule": `Mon 18:00 replicas=0`,
+			},
+			override: OverrideSchedule,
+		},
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.schedule": `Mon 18:00 replicas=0`,
+				"joyrex2001.com/nightshift.ignore":   `True`,
+			},
+			override: OverrideIgnore,
+		},
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.ignore": `false`,
+			},
+			override: OverrideNone,
+		},
+	}
+	for i, tst := range tests {
+		if res := getOverride(tst.data); res != tst.override {
+			t.Errorf("failed test %d - expected: %s, got %s", i, tst.override, res)
+		}
+	}
+}
+
 func TestGetState(t *testing.T) {
 	tests := []struct {
 		data  map[string]string
@@ -166,17 +206,168 @@ func TestGetState(t *testing.T) {
 	}
 }
 
-func TestUpdateState(t *testing.T) {
-	meta := metav1.ObjectMeta{}
-	meta = updateState(meta, 10)
-	st := meta.Annotations["joyrex2001.com/nightshift.savestate"]
-	if st != "10" {
-		t.Errorf("failed test - expected: 10, got %s", st)
+func TestStatePatch(t *testing.T) {
+	tests := []struct {
+		rv    string
+		repl  int
+		patch string
+	}{
+		{rv: "123", repl: 10, patch: `{"metadata":{"annotations":{"joyrex2001.com/nightshift.savestate":"10"},"resourceVersion":"123"}}`},
+		{rv: "456", repl: 0, patch: `{"metadata":{"annotations":{"joyrex2001.com/nightshift.savestate":"0"},"resourceVersion":"456"}}`},
+	}
+	for i, tst := range tests {
+		patch, err := statePatch(tst.rv, tst.repl)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+		}
+		if string(patch) != tst.patch {
+			t.Errorf("failed test %d - expected: %s, got %s", i, tst.patch, patch)
+		}
+	}
+}
+
+func TestGetSnooze(t *testing.T) {
+	until := time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)
+	tests := []struct {
+		data  map[string]string
+		until *time.Time
+		err   bool
+	}{
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.snooze-until": `2019-03-04T23:00:00Z`,
+			},
+			err:   false,
+			until: &until,
+		},
+		{
+			data: map[string]string{
+				"joyrex2001.com/nightshift.snooze-until": `tonight`,
+			},
+			err:   true,
+			until: nil,
+		},
+		{
+			data:  map[string]string{},
+			err:   false,
+			until: nil,
+		},
+	}
+	for i, tst := range tests {
+		res, err := getSnooze(tst.data)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if !tst.err && !reflect.DeepEqual(res, tst.until) {
+			t.Errorf("failed test %d - expected: %v, got %v", i, tst.until, res)
+		}
+	}
+}
+
+func TestSnoozePatch(t *testing.T) {
+	until := time.Date(2019, 3, 4, 23, 0, 0, 0, time.UTC)
+	tests := []struct {
+		until *time.Time
+		patch string
+	}{
+		{until: &until, patch: `{"metadata":{"annotations":{"joyrex2001.com/nightshift.snooze-until":"2019-03-04T23:00:00Z"}}}`},
+		{until: nil, patch: `{"metadata":{"annotations":{"joyrex2001.com/nightshift.snooze-until":null}}}`},
+	}
+	for i, tst := range tests {
+		patch, err := snoozePatch(tst.until)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected error: %s", i, err)
+		}
+		if string(patch) != tst.patch {
+			t.Errorf("failed test %d - expected: %s, got %s", i, tst.patch, patch)
+		}
 	}
-	meta = updateState(meta, 5)
-	st = meta.Annotations["joyrex2001.com/nightshift.savestate"]
-	if st != "5" {
-		t.Errorf("failed test - expected: 5, got %s", st)
+}
+
+func TestRetry(t *testing.T) {
+	retryBackoff = time.Millisecond
+	conflict := errors.NewConflict(schema.GroupResource{Resource: "statefulsets"}, "app", fmt.Errorf("modified"))
+	notfound := errors.NewNotFound(schema.GroupResource{Resource: "statefulsets"}, "app")
+	tests := []struct {
+		errs  []error
+		calls int
+		err   bool
+	}{
+		{errs: []error{nil}, calls: 1, err: false},
+		{errs: []error{conflict, conflict, nil}, calls: 3, err: false},
+		{errs: []error{conflict, notfound}, calls: 2, err: true},
+		{errs: []error{notfound}, calls: 1, err: true},
+		{errs: []error{conflict, conflict, conflict, conflict, conflict}, calls: 5, err: true},
+	}
+	for i, tst := range tests {
+		calls := 0
+		err := retry(func() error {
+			err := tst.errs[calls]
+			calls++
+			return err
+		})
+		if calls != tst.calls {
+			t.Errorf("failed test %d - expected %d calls, got %d", i, tst.calls, calls)
+		}
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error result: %v", i, err)
+		}
+	}
+}
+
+func TestScaleRetry(t *testing.T) {
+	retryBackoff = time.Millisecond
+	gets, puts := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/apis/apps/v1beta1/namespaces/dev/statefulsets/db/scale" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "GET" {
+			gets++
+			if gets == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"ServiceUnavailable","code":503}`))
+				return
+			}
+		} else {
+			puts++
+		}
+		w.Write([]byte(`{"kind":"Scale","apiVersion":"apps/v1beta1","metadata":{"name":"db","namespace":"dev"},"spec":{"replicas":1}}`))
+	}))
+	defer srv.Close()
+
+	scnr := &StatefulSetScanner{kubernetes: &rest.Config{Host: srv.URL}}
+	if err := scnr.Scale(&Object{Namespace: "dev", Name: "db"}, 0); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if gets != 2 || puts != 1 {
+		t.Errorf("failed test - expected unavailable GetScale to be retried, got %d gets and %d puts", gets, puts)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	gr := schema.GroupResource{Resource: "deploymentconfigs"}
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{err: errors.NewConflict(gr, "app", fmt.Errorf("modified")), retryable: true},
+		{err: errors.NewServerTimeout(gr, "update", 1), retryable: true},
+		{err: errors.NewTooManyRequests("slow down", 1), retryable: true},
+		{err: errors.NewServiceUnavailable("unavailable"), retryable: true},
+		{err: errors.NewNotFound(gr, "app"), retryable: false},
+		{err: errors.NewForbidden(gr, "app", fmt.Errorf("denied")), retryable: false},
+		{err: fmt.Errorf("some error"), retryable: false},
+	}
+	for i, tst := range tests {
+		if res := isRetryable(tst.err); res != tst.retryable {
+			t.Errorf("failed test %d - expected %t, got %t", i, tst.retryable, res)
+		}
 	}
 }
 
@@ -2,6 +2,7 @@ package schedule
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -16,6 +17,14 @@ func (s *Schedule) GetReplicas() (int, error) {
 	return strconv.Atoi(r)
 }
 
+// HasReplicas will return true if the schedule defines the number of
+// replicas that should be applied. Schedules without replicas will only
+// handle state and execute triggers.
+func (s *Schedule) HasReplicas() bool {
+	_, ok := s.settings["replicas"]
+	return ok
+}
+
 // GetState will return the state that should be applied according to the
 // schedule.
 func (s *Schedule) GetState() (State, error) {
@@ -33,14 +42,60 @@ func (s *Schedule) GetState() (State, error) {
 	return st, nil
 }
 
-// GetTriggers will return the reference codes of the triggers that should be
-// triggered.
-func (s *Schedule) GetTriggers() []string {
-	trgs := []string{}
-	for _, trg := range strings.Split(s.settings["trigger"], ",") {
-		if trg != "" {
-			trgs = append(trgs, strings.ToLower(trg))
-		}
+// invocationKeys are the settings that contain a list of trigger references.
+var invocationKeys = map[string]bool{"trigger": true, "before": true, "after": true}
+
+// GetTriggers will return the references to the triggers that should be
+// triggered, with their parameters.
+func (s *Schedule) GetTriggers() []Invocation {
+	return s.getInvocations("trigger")
+}
+
+// GetBeforeHooks will return the references to the triggers that should be
+// executed, and succeed, before scaling.
+func (s *Schedule) GetBeforeHooks() []Invocation {
+	return s.getInvocations("before")
+}
+
+// GetAfterHooks will return the references to the triggers that should be
+// executed after scaling succeeded.
+func (s *Schedule) GetAfterHooks() []Invocation {
+	return s.getInvocations("after")
+}
+
+// GetInvocations will return the references to all triggers and hooks of
+// this schedule.
+func (s *Schedule) GetInvocations() []Invocation {
+	res := s.GetTriggers()
+	res = append(res, s.GetBeforeHooks()...)
+	return append(res, s.GetAfterHooks()...)
+}
+
+// getInvocations will return the comma separated trigger references of the
+// given setting. Invalid references are ignored; these are already refused
+// when the schedule is parsed.
+func (s *Schedule) getInvocations(key string) []Invocation {
+	res, err := parseInvocations(strings.ToLower(s.settings[key]))
+	if err != nil {
+		return []Invocation{}
+	}
+	return res
+}
+
+// String will return the trigger reference as it can be used in a schedule,
+// with the parameters sorted by name.
+func (i Invocation) String() string {
+	if len(i.Params) == 0 {
+		return i.Id
+	}
+	keys := []string{}
+	for k := range i.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := []string{}
+	for _, k := range keys {
+		params = append(params, k+"="+i.Params[k])
 	}
-	return trgs
+	return i.Id + "(" + strings.Join(params, ",") + ")"
 }
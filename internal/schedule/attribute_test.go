@@ -51,6 +51,28 @@ func TestGetReplicas(t *testing.T) {
 	}
 }
 
+func TestHasReplicas(t *testing.T) {
+	tests := []struct {
+		sched string
+		has   bool
+	}{
+		{sched: "Mon-Fri 8:00 replicas=1", has: true},
+		{sched: "Mon-Fri 8:00 replicas=0 trigger=foo", has: true},
+		{sched: "Mon-Fri 8:00 trigger=foo", has: false},
+		{sched: "Mon-Fri 8:00", has: false},
+	}
+	for i, tst := range tests {
+		s, err := New(tst.sched)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+			continue
+		}
+		if s.HasReplicas() != tst.has {
+			t.Errorf("failed test %d - expected %v, got %v", i, tst.has, s.HasReplicas())
+		}
+	}
+}
+
 func TestGetState(t *testing.T) {
 	tests := []struct {
 		state State
@@ -181,11 +203,119 @@ func TestGetTriggers(t *testing.T) {
 				},
 			},
 		},
+		{
+			triggers: []string{"refreshdb(db=orders,size=small)", "build"},
+			sched: &Schedule{
+				settings: map[string]string{
+					"trigger": "refreshdb(size=small,db=orders),build",
+				},
+			},
+		},
+		{
+			triggers: []string{"refreshdb(url=http://db:5432)", "refreshdb"},
+			sched: &Schedule{
+				settings: map[string]string{
+					"trigger": "refreshdb(url=http://db:5432,),refreshdb()",
+				},
+			},
+		},
 	}
 	for i, tst := range tests {
-		r := tst.sched.GetTriggers()
+		r := invocationStrings(tst.sched.GetTriggers())
 		if !reflect.DeepEqual(r, tst.triggers) {
 			t.Errorf("failed test %d; expected %#v, got %#v", i, tst.triggers, r)
 		}
 	}
 }
+
+func TestGetHooks(t *testing.T) {
+	tests := []struct {
+		sched  string
+		before []string
+		after  []string
+		all    []string
+	}{
+		{
+			sched:  "Mon-Fri 18:00 replicas=0",
+			before: []string{},
+			after:  []string{},
+			all:    []string{},
+		},
+		{
+			sched:  "Mon-Fri 18:00 replicas=0 before=backup-db after=notify",
+			before: []string{"backup-db"},
+			after:  []string{"notify"},
+			all:    []string{"backup-db", "notify"},
+		},
+		{
+			sched:  "Mon-Fri 18:00 replicas=0 before=Backup-DB,flush trigger=report",
+			before: []string{"backup-db", "flush"},
+			after:  []string{},
+			all:    []string{"report", "backup-db", "flush"},
+		},
+		{
+			sched:  "Mon-Fri 18:00 replicas=0 before=backup(db=orders, mode=full) after=notify(channel=ops)",
+			before: []string{"backup(db=orders,mode=full)"},
+			after:  []string{"notify(channel=ops)"},
+			all:    []string{"backup(db=orders,mode=full)", "notify(channel=ops)"},
+		},
+	}
+	for i, tst := range tests {
+		s, err := New(tst.sched)
+		if err != nil {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+			continue
+		}
+		if r := invocationStrings(s.GetBeforeHooks()); !reflect.DeepEqual(r, tst.before) {
+			t.Errorf("failed test %d; expected before %#v, got %#v", i, tst.before, r)
+		}
+		if r := invocationStrings(s.GetAfterHooks()); !reflect.DeepEqual(r, tst.after) {
+			t.Errorf("failed test %d; expected after %#v, got %#v", i, tst.after, r)
+		}
+		if r := invocationStrings(s.GetInvocations()); !reflect.DeepEqual(r, tst.all) {
+			t.Errorf("failed test %d; expected invocations %#v, got %#v", i, tst.all, r)
+		}
+	}
+}
+
+func TestParseInvocation(t *testing.T) {
+	tests := []struct {
+		text string
+		inv  Invocation
+		err  bool
+	}{
+		{text: "refreshdb", inv: Invocation{Id: "refreshdb"}},
+		{text: "refreshdb()", inv: Invocation{Id: "refreshdb"}},
+		{text: "refreshdb(db=orders,size=)", inv: Invocation{Id: "refreshdb", Params: map[string]string{"db": "orders", "size": ""}}},
+		{text: "refreshdb(db=a=b)", inv: Invocation{Id: "refreshdb", Params: map[string]string{"db": "a=b"}}},
+		{text: "refreshdb(DB=Orders)", inv: Invocation{Id: "refreshdb", Params: map[string]string{"db": "Orders"}}},
+		{text: "(db=orders)", err: true},
+		{text: "refreshdb(db=orders", err: true},
+		{text: "refreshdb(db)", err: true},
+		{text: "refreshdb(=orders)", err: true},
+		{text: "refreshdb(db=(orders))", err: true},
+		{text: "refreshdb)", err: true},
+		{text: "refreshdb=orders", err: true},
+	}
+	for i, tst := range tests {
+		inv, err := parseInvocation(tst.text)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err == nil && !reflect.DeepEqual(inv, tst.inv) {
+			t.Errorf("failed test %d; expected %#v, got %#v", i, tst.inv, inv)
+		}
+	}
+}
+
+// invocationStrings will return the given invocations as strings.
+func invocationStrings(invs []Invocation) []string {
+	res := []string{}
+	for _, inv := range invs {
+		res = append(res, inv.String())
+	}
+	return res
+}
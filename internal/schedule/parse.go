@@ -15,11 +15,14 @@ func (s *Schedule) parse(text string) error {
 	text = trimSpaces(text)
 	text = strings.Replace(text, ", ", ",", -1)
 	text = strings.Replace(text, "- ", "-", -1)
-	text = strings.ToLower(text)
+	text = lowerOutside(text)
 	s.Description = text
-	text = strings.Replace(text, ":", " ", -1)
+	text = strings.Replace(text, ":", " ", 1)
 
-	flds := strings.Split(text, " ")
+	flds := splitOutside(text, ' ')
+	if len(flds) < 3 {
+		return fmt.Errorf("invalid schedule %s", s.Description)
+	}
 	if err := s.parseWeekday(flds[0]); err != nil {
 		return err
 	}
@@ -35,16 +38,120 @@ func (s *Schedule) parse(text string) error {
 	}
 
 	for _, kv := range flds[3:] {
-		kvf := strings.Split(kv, "=")
+		kvf := strings.SplitN(kv, "=", 2)
 		if len(kvf) != 2 {
 			return fmt.Errorf("invalid setting %s", kv)
 		}
+		if invocationKeys[kvf[0]] {
+			if _, err := parseInvocations(kvf[1]); err != nil {
+				return err
+			}
+		} else if strings.ContainsAny(kvf[1], "=()") {
+			return fmt.Errorf("invalid setting %s", kv)
+		}
 		s.settings[kvf[0]] = kvf[1]
 	}
 
 	return nil
 }
 
+// parseInvocations will parse the given comma separated list of trigger
+// references, which can have parameters; e.g. refreshdb(db=orders),build.
+func parseInvocations(text string) ([]Invocation, error) {
+	res := []Invocation{}
+	for _, v := range splitOutside(text, ',') {
+		if v == "" {
+			continue
+		}
+		inv, err := parseInvocation(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, inv)
+	}
+	return res, nil
+}
+
+// parseInvocation will parse the given trigger reference, with the optional
+// comma separated parameters between parentheses.
+func parseInvocation(text string) (Invocation, error) {
+	i := strings.Index(text, "(")
+	if i < 0 {
+		if strings.ContainsAny(text, ")=") {
+			return Invocation{}, fmt.Errorf("invalid trigger reference %s", text)
+		}
+		return Invocation{Id: text}, nil
+	}
+	if i == 0 || !strings.HasSuffix(text, ")") || strings.ContainsAny(text[:i], ")=") {
+		return Invocation{}, fmt.Errorf("invalid trigger reference %s", text)
+	}
+	inv := Invocation{Id: text[:i]}
+	for _, kv := range strings.Split(text[i+1:len(text)-1], ",") {
+		if kv == "" {
+			continue
+		}
+		kvf := strings.SplitN(kv, "=", 2)
+		if len(kvf) != 2 || kvf[0] == "" || strings.ContainsAny(kv, "()") {
+			return Invocation{}, fmt.Errorf("invalid parameter %s in trigger reference %s", kv, text)
+		}
+		if inv.Params == nil {
+			inv.Params = map[string]string{}
+		}
+		inv.Params[strings.ToLower(kvf[0])] = kvf[1]
+	}
+	return inv, nil
+}
+
+// lowerOutside will lowercase the given text, except where it is between
+// parentheses, so the values of trigger parameters keep their case.
+func lowerOutside(text string) string {
+	res := ""
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			if depth == 0 {
+				res += strings.ToLower(text[start:i])
+				start = i
+			}
+			depth++
+		case ')':
+			if depth == 1 {
+				res += text[start:i]
+				start = i
+			}
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+	if depth == 0 {
+		return res + strings.ToLower(text[start:])
+	}
+	return res + text[start:]
+}
+
+// splitOutside will split the given text on the given separator, except
+// where the separator is between parentheses.
+func splitOutside(text string, sep byte) []string {
+	res := []string{}
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				res = append(res, text[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(res, text[start:])
+}
+
 // parseWeekday will parse the weekday definition of the schedule description.
 func (s *Schedule) parseWeekday(text string) error {
 	for _, dp := range strings.Split(text, ",") {
@@ -228,6 +228,53 @@ func TestParse(t *testing.T) {
 				Description: "mon 18:00 replicas=0 trigger=refreshdb,,build,",
 			},
 		},
+		{
+			data: `Mon 18:00 replicas=0 trigger=refreshdb(db=orders, size=small),build`,
+			err:  false,
+			sched: &Schedule{
+				hour: 18,
+				min:  00,
+				dayOfWeek: map[time.Weekday]bool{
+					1: true,
+				},
+				settings: map[string]string{
+					"replicas": "0",
+					"trigger":  "refreshdb(db=orders,size=small),build",
+				},
+				Description: "mon 18:00 replicas=0 trigger=refreshdb(db=orders,size=small),build",
+			},
+		},
+		{
+			data: `Mon 18:00 Replicas=0 Trigger=RefreshDB(DB=Orders,URL=http://DB:5432/Orders),Build`,
+			err:  false,
+			sched: &Schedule{
+				hour: 18,
+				min:  00,
+				dayOfWeek: map[time.Weekday]bool{
+					1: true,
+				},
+				settings: map[string]string{
+					"replicas": "0",
+					"trigger":  "refreshdb(DB=Orders,URL=http://DB:5432/Orders),build",
+				},
+				Description: "mon 18:00 replicas=0 trigger=refreshdb(DB=Orders,URL=http://DB:5432/Orders),build",
+			},
+		},
+		{
+			data:  `Mon 18:00 replicas=0 trigger=refreshdb(db=orders`,
+			err:   true,
+			sched: &Schedule{},
+		},
+		{
+			data:  `Mon 18:00 replicas=0 state=save(db=orders)`,
+			err:   true,
+			sched: &Schedule{},
+		},
+		{
+			data:  `Mon`,
+			err:   true,
+			sched: &Schedule{},
+		},
 	}
 	for i, tst := range tests {
 		s := &Schedule{
@@ -34,6 +34,12 @@ func SetTimeZone(tz string) error {
 	return err
 }
 
+// SetLocation will configure the timezone in which this schedule is defined.
+// If nil is provided, the globally configured timezone is used.
+func (s *Schedule) SetLocation(loc *time.Location) {
+	s.location = loc
+}
+
 // Copy will return a fresh copy of the Schedule object.
 func (s *Schedule) Copy() *Schedule {
 	new := &Schedule{}
@@ -63,6 +69,10 @@ func (s *Schedule) hasDayOfWeek(day time.Weekday) bool {
 
 // getTodayTrigger will get the trigger time if the trigger would run today.
 func (s *Schedule) getTodayTrigger(now time.Time) time.Time {
-	now = now.In(timezone)
-	return time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.min, 0, 0, timezone)
+	loc := timezone
+	if s.location != nil {
+		loc = s.location
+	}
+	now = now.In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.min, 0, 0, loc)
 }
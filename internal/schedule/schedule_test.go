@@ -125,6 +125,12 @@ func TestGetTodayTrigger(t *testing.T) {
 			sched:    &Schedule{hour: 18, min: 0},
 			trigger:  time.Date(2019, 1, 1, 17, 00, 0, 0, time.UTC),
 		},
+		{
+			timezone: "Europe/Amsterdam",
+			now:      time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC),
+			sched:    &Schedule{hour: 18, min: 0, location: mustLoadLocation("America/New_York")},
+			trigger:  time.Date(2019, 1, 1, 23, 00, 0, 0, time.UTC),
+		},
 	}
 	for i, tst := range tests {
 		SetTimeZone(tst.timezone)
@@ -216,3 +222,11 @@ func TestCopy(t *testing.T) {
 		}
 	}
 }
+
+func mustLoadLocation(tz string) *time.Location {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
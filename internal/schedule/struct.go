@@ -13,6 +13,15 @@ type Schedule struct {
 	hour        int
 	min         int
 	settings    map[string]string
+	location    *time.Location
+}
+
+// Invocation is a reference to a trigger in a schedule, together with the
+// parameters that should be passed to the trigger; e.g.
+// refreshdb(db=orders,size=small).
+type Invocation struct {
+	Id     string            `json:"id"`
+	Params map[string]string `json:"params,omitempty"`
 }
 
 // State describes the possible values of the 'state' attribute.
@@ -0,0 +1,382 @@
+package trigger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// AlertmanagerTrigger is the object that implements triggers that silence
+// the alerts of scaled down namespaces in alertmanager, until the namespace
+// is scaled up again. The silences are looked up in alertmanager by their
+// creator and namespace matcher, so they can be updated and expired by any
+// instance of the trigger, also after a reload or a restart.
+type AlertmanagerTrigger struct {
+	config Config
+	m      sync.Mutex
+}
+
+// matcher is an alertmanager silence matcher.
+type matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual bool   `json:"isEqual"`
+}
+
+// silence is the body of an alertmanager silence.
+type silence struct {
+	ID        string    `json:"id,omitempty"`
+	Matchers  []matcher `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+}
+
+// gettableSilence is a silence as returned by alertmanager, including its
+// state (active, pending or expired).
+type gettableSilence struct {
+	silence
+	Status struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+// matcherRegex matches matchers as name=value, name!=value, name=~regex and
+// name!~regex.
+var matcherRegex = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*"?(.*?)"?\s*$`)
+
+func init() {
+	RegisterModule("alertmanager", NewAlertmanagerTrigger)
+}
+
+// NewAlertmanagerTrigger will instantiate a new AlertmanagerTrigger object.
+func NewAlertmanagerTrigger() (Trigger, error) {
+	return &AlertmanagerTrigger{config: Config{}}, nil
+}
+
+// SetConfig will set the generic configuration for this trigger.
+func (s *AlertmanagerTrigger) SetConfig(cfg Config) {
+	s.config = cfg
+}
+
+// GetConfig will return the config applied for this trigger.
+func (s *AlertmanagerTrigger) GetConfig() Config {
+	return s.config
+}
+
+// Execute will create a silence for each namespace of the objects in the
+// given event that have been scaled down, lasting until the next scheduled
+// scale up of these objects. If there is no scheduled scale up, the silence
+// will last for the configured duration. Silences of namespaces of which the
+// objects have been scaled up are expired.
+func (s *AlertmanagerTrigger) Execute(evt Event) error {
+	settings, err := ResolveSettings(s.config.Settings)
+	if err != nil {
+		return err
+	}
+	if len(evt.Objects) == 0 {
+		return fmt.Errorf("no objects to silence")
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	errs := []string{}
+	for _, ns := range namespaces(evt.Objects) {
+		endsAt, down, err := silenceUntil(evt, ns, settings["duration"])
+		if err == nil && down {
+			err = s.silence(settings, evt, ns, endsAt)
+		}
+		if err == nil && !down {
+			err = s.expire(settings, ns)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", ns, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("error silencing alerts; %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// Wake will expire the silences of the namespaces of the given woken objects,
+// unless other objects in the namespace are still asleep; in that case the
+// namespace as a whole is still scaled down, and the silence is kept.
+func (s *AlertmanagerTrigger) Wake(woken, asleep []EventObject) error {
+	settings, err := ResolveSettings(s.config.Settings)
+	if err != nil {
+		return err
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	sleeping := map[string]bool{}
+	for _, ns := range namespaces(asleep) {
+		sleeping[ns] = true
+	}
+	errs := []string{}
+	for _, ns := range namespaces(woken) {
+		if sleeping[ns] {
+			glog.V(4).Infof("Not expiring silence of %s; other objects are still scaled down", ns)
+			continue
+		}
+		if err := s.expire(settings, ns); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", ns, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("error expiring silences; %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// namespaces will return the sorted unique namespaces of the given objects.
+func namespaces(objs []EventObject) []string {
+	seen := map[string]bool{}
+	res := []string{}
+	for _, obj := range objs {
+		if !seen[obj.Namespace] {
+			seen[obj.Namespace] = true
+			res = append(res, obj.Namespace)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// silenceUntil will return the time until which the given namespace should
+// be silenced; this is the latest next scale up of the objects in the
+// namespace that have been scaled down. If none of these objects have a next
+// scale up, the event time plus the given duration (default 24h) is used. It
+// will return false if none of the objects in the namespace have been scaled
+// down.
+func silenceUntil(evt Event, ns, duration string) (time.Time, bool, error) {
+	var until time.Time
+	down := false
+	for _, obj := range evt.Objects {
+		if obj.Namespace != ns || obj.NewReplicas > 0 {
+			continue
+		}
+		down = true
+		if obj.NextScaleUp != nil && obj.NextScaleUp.After(until) {
+			until = *obj.NextScaleUp
+		}
+	}
+	if !down || !until.IsZero() {
+		return until, down, nil
+	}
+	dur, err := getDuration(duration, "24h")
+	if err != nil {
+		return until, down, err
+	}
+	start := evt.Time
+	if start.IsZero() {
+		start = time.Now()
+	}
+	return start.Add(dur), down, nil
+}
+
+// silence will create, or update, the silence for given namespace, lasting
+// until the given time.
+func (s *AlertmanagerTrigger) silence(settings map[string]string, evt Event, ns string, endsAt time.Time) error {
+	now := time.Now()
+	if !endsAt.After(now) {
+		glog.V(4).Infof("Not silencing %s; scale up at %s has passed", ns, endsAt)
+		return nil
+	}
+	values := evt.Values(settings)
+	values["Namespace"] = ns
+	values["EndsAt"] = endsAt
+	matchers, err := s.getMatchers(settings, values, ns)
+	if err != nil {
+		return err
+	}
+	comment := settings["comment"]
+	if comment == "" {
+		comment = "Scaled down by nightshift until {{ .EndsAt.Format \"2006-01-02 15:04\" }}"
+	}
+	if comment, err = RenderTemplate(comment, values); err != nil {
+		return err
+	}
+	existing, err := s.findSilences(settings, ns)
+	if err != nil {
+		return err
+	}
+	sil := silence{
+		Matchers:  matchers,
+		StartsAt:  now,
+		EndsAt:    endsAt,
+		CreatedBy: createdBy(settings),
+		Comment:   comment,
+	}
+	if len(existing) > 0 {
+		sil.ID = existing[0].ID
+	}
+	id, err := s.postSilence(settings, sil)
+	if serr, ok := err.(*StatusError); ok && serr.StatusCode == http.StatusNotFound && sil.ID != "" {
+		// the silence is no longer known; create a new one instead
+		sil.ID = ""
+		id, err = s.postSilence(settings, sil)
+	}
+	if err != nil {
+		return err
+	}
+	glog.V(4).Infof("Silenced %s until %s (%s)", ns, endsAt, id)
+	return nil
+}
+
+// expire will expire the silences of given namespace, if there are any.
+func (s *AlertmanagerTrigger) expire(settings map[string]string, ns string) error {
+	sils, err := s.findSilences(settings, ns)
+	if err != nil {
+		return err
+	}
+	for _, sil := range sils {
+		err := s.do(settings, "DELETE", "/api/v2/silence/"+url.PathEscape(sil.ID), nil, nil)
+		if serr, ok := err.(*StatusError); err != nil && (!ok || serr.StatusCode != http.StatusNotFound) {
+			return err
+		}
+		glog.V(4).Infof("Expired silence of %s (%s)", ns, sil.ID)
+	}
+	return nil
+}
+
+// findSilences will return the silences of given namespace that have been
+// created by this trigger and have not expired yet. These are the silences
+// with the configured creator, that match the namespace on the namespace
+// label.
+func (s *AlertmanagerTrigger) findSilences(settings map[string]string, ns string) ([]gettableSilence, error) {
+	label := namespaceLabel(settings)
+	query := url.Values{"filter": {fmt.Sprintf("%s=%q", label, ns)}}
+	sils := []gettableSilence{}
+	if err := s.do(settings, "GET", "/api/v2/silences?"+query.Encode(), nil, &sils); err != nil {
+		return nil, err
+	}
+	res := []gettableSilence{}
+	for _, sil := range sils {
+		if sil.Status.State == "expired" || sil.CreatedBy != createdBy(settings) {
+			continue
+		}
+		for _, m := range sil.Matchers {
+			if m.Name == label && m.Value == ns && !m.IsRegex {
+				res = append(res, sil)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// namespaceLabel will return the label that is used to match the namespace,
+// as configured with namespacelabel, default namespace.
+func namespaceLabel(settings map[string]string) string {
+	if label := settings["namespacelabel"]; label != "" {
+		return label
+	}
+	return "namespace"
+}
+
+// createdBy will return the creator of the silences, as configured with
+// createdby, default nightshift.
+func createdBy(settings map[string]string) string {
+	if by := settings["createdby"]; by != "" {
+		return by
+	}
+	return "nightshift"
+}
+
+// getMatchers will return the matchers of the silence of given namespace;
+// this is the namespace label (as configured with namespacelabel, default
+// namespace), and the matchers configured with matchers, one per line.
+func (s *AlertmanagerTrigger) getMatchers(settings map[string]string, values map[string]interface{}, ns string) ([]matcher, error) {
+	res := []matcher{{Name: namespaceLabel(settings), Value: ns, IsEqual: true}}
+	lines, err := renderLines(settings["matchers"], values)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		m := matcherRegex.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("invalid matcher '%s'", line)
+		}
+		res = append(res, matcher{
+			Name:    m[1],
+			Value:   m[3],
+			IsRegex: strings.HasSuffix(m[2], "~"),
+			IsEqual: !strings.HasPrefix(m[2], "!"),
+		})
+	}
+	return res, nil
+}
+
+// postSilence will post the given silence to alertmanager, and return the id
+// of the silence.
+func (s *AlertmanagerTrigger) postSilence(settings map[string]string, sil silence) (string, error) {
+	res := struct {
+		SilenceID string `json:"silenceID"`
+	}{}
+	if err := s.do(settings, "POST", "/api/v2/silences", sil, &res); err != nil {
+		return "", err
+	}
+	if res.SilenceID == "" {
+		return "", fmt.Errorf("no silence id returned")
+	}
+	return res.SilenceID, nil
+}
+
+// do will call the alertmanager api at given path, with given body as json,
+// and decode the json response into given result, if not nil.
+func (s *AlertmanagerTrigger) do(settings map[string]string, method, path string, body, result interface{}) error {
+	base := strings.TrimRight(strings.TrimSpace(settings["url"]), "/")
+	if base == "" {
+		return fmt.Errorf("no url specified")
+	}
+	timeout, err := getDuration(settings["timeout"], "5s")
+	if err != nil {
+		return err
+	}
+	tlscfg, err := getTLSConfig(settings)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	if body != nil {
+		if err := json.NewEncoder(buf).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, base+path, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	cli := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlscfg},
+	}
+	defer cli.CloseIdleConnections()
+	resp, err := cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := ioutil.ReadAll(resp.Body)
+	glog.V(5).Infof("url: %s, status: %s, body: %s", req.URL, resp.Status, data)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &StatusError{Status: resp.Status, StatusCode: resp.StatusCode}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
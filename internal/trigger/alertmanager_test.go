@@ -0,0 +1,281 @@
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAlertmanager is a minimal alertmanager api that supports listing,
+// creating, updating and expiring silences.
+type fakeAlertmanager struct {
+	m        sync.Mutex
+	silences map[string]silence
+	created  int
+	updated  int
+	expired  int
+}
+
+func (f *fakeAlertmanager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/api/v2/silences":
+		res := []gettableSilence{}
+		for _, sil := range f.silences {
+			gs := gettableSilence{silence: sil}
+			gs.Status.State = "active"
+			res = append(res, gs)
+		}
+		json.NewEncoder(w).Encode(res)
+	case r.Method == "POST" && r.URL.Path == "/api/v2/silences":
+		sil := silence{}
+		if err := json.NewDecoder(r.Body).Decode(&sil); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if sil.ID != "" {
+			if _, ok := f.silences[sil.ID]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			f.updated++
+		} else {
+			f.created++
+			sil.ID = fmt.Sprintf("silence-%d", f.created)
+		}
+		f.silences[sil.ID] = sil
+		fmt.Fprintf(w, `{"silenceID":"%s"}`, sil.ID)
+	case r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/api/v2/silence/"):
+		id := strings.TrimPrefix(r.URL.Path, "/api/v2/silence/")
+		if _, ok := f.silences[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.expired++
+		delete(f.silences, id)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// find will return the silence of given namespace, if any.
+func (f *fakeAlertmanager) find(ns string) (silence, bool) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	for _, sil := range f.silences {
+		for _, m := range sil.Matchers {
+			if m.Name == "namespace" && m.Value == ns {
+				return sil, true
+			}
+		}
+	}
+	return silence{}, false
+}
+
+func TestSilenceUntil(t *testing.T) {
+	now := time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC)
+	early := now.Add(14 * time.Hour)
+	late := now.Add(62 * time.Hour)
+	tests := []struct {
+		objs     []EventObject
+		duration string
+		until    time.Time
+		down     bool
+		err      bool
+	}{
+		{
+			objs: []EventObject{
+				{Namespace: "dev", NextScaleUp: &early},
+				{Namespace: "dev", NextScaleUp: &late},
+				{Namespace: "test"},
+			},
+			until: late,
+			down:  true,
+		},
+		{
+			objs:  []EventObject{{Namespace: "dev"}},
+			until: now.Add(24 * time.Hour),
+			down:  true,
+		},
+		{
+			objs:     []EventObject{{Namespace: "dev"}},
+			duration: "2h",
+			until:    now.Add(2 * time.Hour),
+			down:     true,
+		},
+		{
+			objs: []EventObject{{Namespace: "dev", NewReplicas: 1, NextScaleUp: &late}},
+			down: false,
+		},
+		{
+			objs:     []EventObject{{Namespace: "dev"}},
+			duration: "forever",
+			down:     true,
+			err:      true,
+		},
+	}
+	for i, tst := range tests {
+		until, down, err := silenceUntil(Event{Time: now, Objects: tst.objs}, "dev", tst.duration)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err == nil && (!until.Equal(tst.until) || down != tst.down) {
+			t.Errorf("failed test %d - expected %s (%t), got %s (%t)", i, tst.until, tst.down, until, down)
+		}
+	}
+}
+
+func TestGetMatchers(t *testing.T) {
+	tests := []struct {
+		settings map[string]string
+		matchers []matcher
+		err      bool
+	}{
+		{
+			settings: map[string]string{},
+			matchers: []matcher{{Name: "namespace", Value: "dev", IsEqual: true}},
+		},
+		{
+			settings: map[string]string{
+				"namespacelabel": "kubernetes_namespace",
+				"matchers":       "severity!=critical\nalertname=~\"Kube.*\"\njob!~blackbox.*\ncluster={{ .ScannerId }}",
+			},
+			matchers: []matcher{
+				{Name: "kubernetes_namespace", Value: "dev", IsEqual: true},
+				{Name: "severity", Value: "critical", IsEqual: false},
+				{Name: "alertname", Value: "Kube.*", IsRegex: true, IsEqual: true},
+				{Name: "job", Value: "blackbox.*", IsRegex: true, IsEqual: false},
+				{Name: "cluster", Value: "prod", IsEqual: true},
+			},
+		},
+		{
+			settings: map[string]string{"matchers": "severity"},
+			err:      true,
+		},
+	}
+	for i, tst := range tests {
+		trgr := &AlertmanagerTrigger{}
+		values := Event{ScannerId: "prod"}.Values(tst.settings)
+		matchers, err := trgr.getMatchers(tst.settings, values, "dev")
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if err == nil && fmt.Sprintf("%v", matchers) != fmt.Sprintf("%v", tst.matchers) {
+			t.Errorf("failed test %d - expected %v, got %v", i, tst.matchers, matchers)
+		}
+	}
+}
+
+func TestAlertmanagerExecute(t *testing.T) {
+	am := &fakeAlertmanager{silences: map[string]silence{}}
+	srv := httptest.NewServer(am)
+	defer srv.Close()
+
+	settings := map[string]string{
+		"url":     srv.URL,
+		"comment": "{{ .Namespace }} sleeps until {{ .EndsAt.Format \"15:04\" }}",
+	}
+	newTrigger := func() *AlertmanagerTrigger {
+		trgr, _ := NewAlertmanagerTrigger()
+		trgr.SetConfig(Config{Id: "silence", Settings: settings})
+		return trgr.(*AlertmanagerTrigger)
+	}
+	scaleUp := time.Now().Add(14 * time.Hour).Truncate(time.Minute)
+	down := Event{
+		Time: time.Now(),
+		Objects: []EventObject{
+			{Namespace: "dev", Name: "app", NextScaleUp: &scaleUp},
+			{Namespace: "test", Name: "app", NextScaleUp: &scaleUp},
+		},
+	}
+
+	// scaling down will create a silence per namespace
+	if err := newTrigger().Execute(down); err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+	if am.created != 2 || len(am.silences) != 2 {
+		t.Errorf("failed test - expected 2 silences, got %v", am.silences)
+	}
+	sil, _ := am.find("dev")
+	if !sil.EndsAt.Equal(scaleUp) || sil.CreatedBy != "nightshift" || sil.Comment != "dev sleeps until "+scaleUp.Format("15:04") {
+		t.Errorf("failed test - unexpected silence %v", sil)
+	}
+	if len(sil.Matchers) != 1 || sil.Matchers[0].Name != "namespace" || sil.Matchers[0].Value != "dev" {
+		t.Errorf("failed test - unexpected matchers %v", sil.Matchers)
+	}
+
+	// scaling down again will update the existing silences, also after a
+	// restart
+	if err := newTrigger().Execute(down); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if am.created != 2 || am.updated != 2 {
+		t.Errorf("failed test - expected 2 updated silences, got %d created and %d updated", am.created, am.updated)
+	}
+
+	// silences created by others are left alone
+	other := silence{ID: "other", CreatedBy: "someone", Matchers: []matcher{{Name: "namespace", Value: "dev", IsEqual: true}}}
+	am.silences["other"] = other
+	if err := newTrigger().Execute(Event{Objects: down.Objects[:1]}); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if am.created != 2 || am.updated != 3 || !reflect.DeepEqual(am.silences["other"], other) {
+		t.Errorf("failed test - expected other silence to be left alone, got %v", am.silences)
+	}
+	delete(am.silences, "other")
+
+	// waking up a single object will keep the silence while other objects in
+	// the namespace are still asleep
+	woken := []EventObject{{Namespace: "dev", Name: "app", NewReplicas: 1}}
+	asleep := []EventObject{{Namespace: "dev", Name: "db"}}
+	if err := newTrigger().Wake(woken, asleep); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if _, ok := am.find("dev"); !ok || am.expired != 0 {
+		t.Errorf("failed test - expected dev silence to be kept, got %v", am.silences)
+	}
+
+	// waking up the namespace will expire the silence
+	if err := newTrigger().Wake(woken, nil); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if _, ok := am.find("dev"); ok || am.expired != 1 || len(am.silences) != 1 {
+		t.Errorf("failed test - expected dev silence to be expired, got %v", am.silences)
+	}
+
+	// scaling up will expire the silence
+	up := Event{Objects: []EventObject{{Namespace: "test", NewReplicas: 2}}}
+	if err := newTrigger().Execute(up); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if am.expired != 2 || len(am.silences) != 0 {
+		t.Errorf("failed test - expected all silences to be expired, got %v", am.silences)
+	}
+
+	// errors
+	if err := newTrigger().Execute(Event{}); err == nil {
+		t.Errorf("failed test - expected err for event without objects")
+	}
+	settings["url"] = ""
+	if err := newTrigger().Execute(down); err == nil {
+		t.Errorf("failed test - expected err without url")
+	}
+	settings["url"] = srv.URL
+	settings["matchers"] = "invalid"
+	if err := newTrigger().Execute(down); err == nil {
+		t.Errorf("failed test - expected err for invalid matcher")
+	}
+}
@@ -0,0 +1,224 @@
+package trigger
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// EmailTrigger is the object that implements triggers that send an email
+// through a smtp server.
+type EmailTrigger struct {
+	config Config
+}
+
+func init() {
+	RegisterModule("email", NewEmailTrigger)
+}
+
+// NewEmailTrigger will instantiate a new EmailTrigger object.
+func NewEmailTrigger() (Trigger, error) {
+	return &EmailTrigger{config: Config{}}, nil
+}
+
+// SetConfig will set the generic configuration for this trigger.
+func (s *EmailTrigger) SetConfig(cfg Config) {
+	s.config = cfg
+}
+
+// GetConfig will return the config applied for this trigger.
+func (s *EmailTrigger) GetConfig() Config {
+	return s.config
+}
+
+// Execute will send the configured email. The given event is available in
+// the templates of the recipients, subject and body.
+func (s *EmailTrigger) Execute(evt Event) error {
+	settings, err := ResolveSettings(s.config.Settings)
+	if err != nil {
+		return err
+	}
+	values := evt.Values(settings)
+	from, err := RenderTemplate(strings.TrimSpace(settings["from"]), values)
+	if err != nil {
+		return err
+	}
+	if from == "" {
+		return fmt.Errorf("no from address specified")
+	}
+	to, err := getAddresses(settings["to"], values)
+	if err != nil {
+		return err
+	}
+	cc, err := getAddresses(settings["cc"], values)
+	if err != nil {
+		return err
+	}
+	if len(to)+len(cc) == 0 {
+		return fmt.Errorf("no recipients specified")
+	}
+	subject, err := RenderTemplate(settings["subject"], values)
+	if err != nil {
+		return err
+	}
+	body, err := RenderTemplate(settings["body"], values)
+	if err != nil {
+		return err
+	}
+	msg, err := newMessage(from, to, cc, subject, body, settings["contenttype"])
+	if err != nil {
+		return err
+	}
+	return s.send(settings, from, append(to, cc...), msg)
+}
+
+// send will deliver the given message to the given recipients through the
+// smtp server configured with the host and port settings. If starttls is
+// enabled, the connection is upgraded to tls before authenticating with the
+// username and password settings.
+func (s *EmailTrigger) send(settings map[string]string, from string, rcpts []string, msg []byte) error {
+	host := strings.TrimSpace(settings["host"])
+	if host == "" {
+		return fmt.Errorf("no smtp host specified")
+	}
+	port := strings.TrimSpace(settings["port"])
+	if port == "" {
+		port = "25"
+	}
+	timeout, err := getDuration(settings["timeout"], "30s")
+	if err != nil {
+		return err
+	}
+	starttls := false
+	if v := settings["starttls"]; v != "" {
+		if starttls, err = strconv.ParseBool(v); err != nil {
+			return fmt.Errorf("invalid startTLS '%s'", v)
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, port), timeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if starttls {
+		tlscfg, err := getTLSConfig(settings)
+		if err != nil {
+			return err
+		}
+		tlscfg.ServerName = host
+		if err := c.StartTLS(tlscfg); err != nil {
+			return err
+		}
+	}
+	if user := settings["username"]; user != "" {
+		if err := c.Auth(smtp.PlainAuth("", user, settings["password"], host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(address(from)); err != nil {
+		return err
+	}
+	for _, rcpt := range rcpts {
+		if err := c.Rcpt(address(rcpt)); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	glog.V(5).Infof("host: %s, from: %s, to: %v", host, from, rcpts)
+	return c.Quit()
+}
+
+// getAddresses will render the given comma or newline separated list of
+// addresses with the given values, and return the non empty addresses.
+func getAddresses(text string, values map[string]interface{}) ([]string, error) {
+	lines, err := renderLines(text, values)
+	if err != nil {
+		return nil, err
+	}
+	res := []string{}
+	for _, l := range lines {
+		for _, addr := range strings.Split(l, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				if _, err := mail.ParseAddress(addr); err != nil {
+					return nil, fmt.Errorf("invalid address '%s': %s", addr, err)
+				}
+				res = append(res, addr)
+			}
+		}
+	}
+	return res, nil
+}
+
+// address will return the plain email address of the given address, which
+// may include a display name (e.g. "Nightshift <nightshift@example.com>").
+func address(addr string) string {
+	a, err := mail.ParseAddress(addr)
+	if err != nil {
+		return addr
+	}
+	return a.Address
+}
+
+// newMessage will return the email message with given headers and body. The
+// body is quoted-printable encoded, and of the given content type (default
+// text/plain).
+func newMessage(from string, to, cc []string, subject, body, contentType string) ([]byte, error) {
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("invalid address '%s': %s", from, err)
+	}
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	if strings.ContainsAny(contentType, "\r\n") {
+		return nil, fmt.Errorf("invalid contentType '%s'", contentType)
+	}
+	subject = strings.Join(strings.Fields(subject), " ")
+
+	msg := &bytes.Buffer{}
+	fmt.Fprintf(msg, "From: %s\r\n", from)
+	if len(to) > 0 {
+		fmt.Fprintf(msg, "To: %s\r\n", strings.Join(to, ", "))
+	}
+	if len(cc) > 0 {
+		fmt.Fprintf(msg, "Cc: %s\r\n", strings.Join(cc, ", "))
+	}
+	fmt.Fprintf(msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(msg, "Content-Type: %s\r\n", contentType)
+	fmt.Fprintf(msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(msg)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,295 @@
+package trigger
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpStub is a minimal smtp server that accepts mail, optionally after
+// STARTTLS and PLAIN authentication, and records the received messages.
+type smtpStub struct {
+	ln       net.Listener
+	tls      *tls.Config
+	username string
+	password string
+	m        sync.Mutex
+	mails    []smtpMail
+}
+
+// smtpMail is a mail as received by the smtpStub.
+type smtpMail struct {
+	from string
+	rcpt []string
+	data string
+	tls  bool
+	auth bool
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed test - unable to listen: %s", err)
+	}
+	s := &smtpStub{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStub) port() string {
+	return strings.Split(s.ln.Addr().String(), ":")[1]
+}
+
+func (s *smtpStub) received() []smtpMail {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]smtpMail{}, s.mails...)
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	rd := bufio.NewReader(conn)
+	reply := func(msg string) { conn.Write([]byte(msg + "\r\n")) }
+	mail := smtpMail{}
+	reply("220 localhost ESMTP stub")
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case cmd == "EHLO":
+			ext := "250-localhost\r\n250-AUTH PLAIN\r\n"
+			if s.tls != nil && !mail.tls {
+				ext += "250-STARTTLS\r\n"
+			}
+			reply(ext + "250 8BITMIME")
+		case cmd == "STARTTLS":
+			reply("220 ready to start tls")
+			tconn := tls.Server(conn, s.tls)
+			if err := tconn.Handshake(); err != nil {
+				return
+			}
+			conn, rd = tconn, bufio.NewReader(tconn)
+			mail.tls = true
+		case cmd == "AUTH":
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			if string(creds) != "\x00"+s.username+"\x00"+s.password {
+				reply("535 authentication failed")
+				continue
+			}
+			mail.auth = true
+			reply("235 authenticated")
+		case cmd == "MAIL":
+			mail.from = strings.TrimSuffix(strings.TrimPrefix(line, "MAIL FROM:<"), ">")
+			mail.from = strings.Split(mail.from, ">")[0]
+			reply("250 ok")
+		case cmd == "RCPT":
+			rcpt := strings.TrimSuffix(strings.TrimPrefix(line, "RCPT TO:<"), ">")
+			if strings.HasPrefix(rcpt, "unknown@") {
+				reply("550 no such user")
+				continue
+			}
+			mail.rcpt = append(mail.rcpt, rcpt)
+			reply("250 ok")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			data := []string{}
+			for {
+				l, err := rd.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data = append(data, l)
+			}
+			mail.data = strings.Join(data, "")
+			s.m.Lock()
+			s.mails = append(s.mails, mail)
+			s.m.Unlock()
+			reply("250 queued")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func TestEmailExecute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nightshift")
+	if err != nil {
+		t.Fatalf("failed test - unable to create tempdir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	cert, key, _ := writeCertificate(t, dir, "smtp")
+	pair, err := tls.LoadX509KeyPair(cert, key)
+	if err != nil {
+		t.Fatalf("failed test - unable to load certificate: %s", err)
+	}
+
+	srv := newSMTPStub(t)
+	defer srv.ln.Close()
+	srv.tls = &tls.Config{Certificates: []tls.Certificate{pair}}
+	srv.username, srv.password = "nightshift", "s3cr3t"
+
+	base := map[string]string{
+		"host":    "127.0.0.1",
+		"port":    srv.port(),
+		"from":    "Nightshift <nightshift@example.com>",
+		"to":      "{{ range .Objects }}{{ .Namespace }}@example.com,{{ end }}",
+		"subject": "Your environment {{ .ScannerId }} goes to sleep at {{ .Time.Format \"15:04\" }}",
+		"body":    "{{ range .Objects }}{{ .Name }} will be scaled to {{ .NewReplicas }}\n{{ end }}",
+	}
+	tests := []struct {
+		settings map[string]string
+		rcpt     []string
+		tls      bool
+		auth     bool
+		contains []string
+		err      bool
+	}{
+		{
+			settings: map[string]string{},
+			rcpt:     []string{"development@example.com", "test@example.com"},
+			contains: []string{
+				"From: Nightshift <nightshift@example.com>\r\n",
+				"To: development@example.com, test@example.com\r\n",
+				"Subject: Your environment dev goes to sleep at 18:00\r\n",
+				"Content-Type: text/plain; charset=utf-8\r\n",
+				"app will be scaled to 0\r\ndb will be scaled to 0",
+			},
+		},
+		{
+			settings: map[string]string{"cc": "ops@example.com\nmanagers@example.com", "subject": "Schlafenszeit für {{ .ScannerId }}"},
+			rcpt:     []string{"development@example.com", "test@example.com", "ops@example.com", "managers@example.com"},
+			contains: []string{
+				"Cc: ops@example.com, managers@example.com\r\n",
+				"Subject: =?utf-8?q?Schlafenszeit_f=C3=BCr_dev?=\r\n",
+			},
+		},
+		{
+			settings: map[string]string{"starttls": "true", "cafile": cert, "username": "nightshift", "password": "s3cr3t"},
+			rcpt:     []string{"development@example.com", "test@example.com"},
+			tls:      true,
+			auth:     true,
+		},
+		{
+			settings: map[string]string{"starttls": "true", "insecureskipverify": "true", "contenttype": "text/html"},
+			rcpt:     []string{"development@example.com", "test@example.com"},
+			tls:      true,
+			contains: []string{"Content-Type: text/html\r\n"},
+		},
+		{
+			settings: map[string]string{"starttls": "true"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"starttls": "true", "cafile": cert, "username": "nightshift", "password": "wrong"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"to": "unknown@example.com"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"to": "not an address"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"to": ""},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"from": ""},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"host": ""},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"starttls": "maybe"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"body": "{{ .Foo"},
+			err:      true,
+		},
+	}
+
+	evt := Event{
+		Time:      time.Date(2019, 1, 7, 18, 0, 0, 0, time.UTC),
+		ScannerId: "dev",
+		Objects: []EventObject{
+			{Namespace: "development", Name: "app"},
+			{Namespace: "test", Name: "db"},
+		},
+	}
+	for i, tst := range tests {
+		settings := map[string]string{}
+		for k, v := range base {
+			settings[k] = v
+		}
+		for k, v := range tst.settings {
+			settings[k] = v
+		}
+		before := len(srv.received())
+		trgr, _ := NewEmailTrigger()
+		trgr.SetConfig(Config{Id: "notify", Settings: settings})
+		err := trgr.Execute(evt)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+			continue
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		mails := srv.received()[before:]
+		if tst.err {
+			if len(mails) != 0 {
+				t.Errorf("failed test %d - expected no mail, got %d", i, len(mails))
+			}
+			continue
+		}
+		if len(mails) != 1 {
+			t.Errorf("failed test %d - expected 1 mail, got %d", i, len(mails))
+			continue
+		}
+		mail := mails[0]
+		if mail.from != "nightshift@example.com" {
+			t.Errorf("failed test %d - unexpected from %s", i, mail.from)
+		}
+		if strings.Join(mail.rcpt, ",") != strings.Join(tst.rcpt, ",") {
+			t.Errorf("failed test %d - expected rcpt %v, got %v", i, tst.rcpt, mail.rcpt)
+		}
+		if mail.tls != tst.tls || mail.auth != tst.auth {
+			t.Errorf("failed test %d - expected tls=%t auth=%t, got tls=%t auth=%t", i, tst.tls, tst.auth, mail.tls, mail.auth)
+		}
+		for _, c := range tst.contains {
+			if !strings.Contains(mail.data, c) {
+				t.Errorf("failed test %d - expected mail to contain %q, got %q", i, c, mail.data)
+			}
+		}
+	}
+}
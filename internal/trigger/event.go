@@ -0,0 +1,59 @@
+package trigger
+
+import (
+	"time"
+)
+
+// Event describes the scheduled event that caused a trigger to be executed.
+// If multiple objects referred to the same trigger at the same time, the
+// trigger is executed once, and all objects are included in the Objects
+// list. The ScannerId and Schedule are those of the first object. If the
+// trigger was executed by a schedule of its own, the Objects list is empty.
+// Params are the parameters given with the trigger reference in the
+// schedule, e.g. trigger=refreshdb(db=orders).
+type Event struct {
+	Time      time.Time         `json:"time"`
+	ScannerId string            `json:"scanner_id"`
+	Schedule  string            `json:"schedule"`
+	Params    map[string]string `json:"params,omitempty"`
+	Objects   []EventObject     `json:"objects"`
+}
+
+// EventObject describes an object that has been scaled by the scheduled
+// event that caused the trigger to be executed. NextScaleUp is the time of
+// the next scheduled event that will scale the object up again, if any.
+type EventObject struct {
+	Namespace   string     `json:"namespace"`
+	Name        string     `json:"name"`
+	UID         string     `json:"uid"`
+	ScannerId   string     `json:"scanner_id"`
+	Schedule    string     `json:"schedule"`
+	OldReplicas int        `json:"old_replicas"`
+	NewReplicas int        `json:"new_replicas"`
+	NextScaleUp *time.Time `json:"next_scale_up,omitempty"`
+}
+
+// Values will return the values that are available when rendering the
+// templates in the trigger settings. These are the (lowercase) settings of
+// the trigger itself, e.g. {{ .url }}, as well as the event context, e.g.
+// {{ .Time }}, {{ .ScannerId }}, {{ .Schedule }}, {{ .Params }} and
+// {{ range .Objects }}. The parameters of the event are only available in
+// {{ .Params }}; schedules can be set by the owners of the objects, and should
+// not be able to override the settings of the trigger.
+func (e Event) Values(settings map[string]string) map[string]interface{} {
+	values := map[string]interface{}{}
+	for k, v := range settings {
+		values[k] = v
+	}
+	params := map[string]string{}
+	for k, v := range e.Params {
+		params[k] = v
+	}
+	values["Params"] = params
+	values["Time"] = e.Time
+	values["ScannerId"] = e.ScannerId
+	values["Schedule"] = e.Schedule
+	values["Objects"] = e.Objects
+	values["Settings"] = settings
+	return values
+}
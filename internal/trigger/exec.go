@@ -0,0 +1,186 @@
+package trigger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// maxOutput is the maximum number of bytes of the output of a command
+	// that is included in the error of a failed command.
+	maxOutput = 1024
+	// killWait is the time to wait for a command to finish after it has been
+	// killed because it timed out.
+	killWait = time.Second
+)
+
+// ExecError is the error that is returned when a command exits with a non
+// zero exit code, or does not finish in time.
+type ExecError struct {
+	ExitCode int
+	Stderr   string
+	timeout  bool
+	err      error
+}
+
+// Error will return the error message, as required by the error interface.
+func (e *ExecError) Error() string {
+	if e.timeout {
+		return fmt.Sprintf("error exec; command timed out: %s", e.err)
+	}
+	return fmt.Sprintf("error exec; exitcode=%d: %s", e.ExitCode, e.Stderr)
+}
+
+// Timeout will return true if the command did not finish in time; this allows
+// retrying on timeouts.
+func (e *ExecError) Timeout() bool {
+	return e.timeout
+}
+
+// Temporary will return true if the command did not finish in time.
+func (e *ExecError) Temporary() bool {
+	return e.timeout
+}
+
+// ExecTrigger is the object that implements triggers that run a local
+// command.
+type ExecTrigger struct {
+	config Config
+}
+
+func init() {
+	RegisterModule("exec", NewExecTrigger)
+}
+
+// NewExecTrigger will instantiate a new ExecTrigger object.
+func NewExecTrigger() (Trigger, error) {
+	return &ExecTrigger{config: Config{}}, nil
+}
+
+// SetConfig will set the generic configuration for this trigger.
+func (s *ExecTrigger) SetConfig(cfg Config) {
+	s.config = cfg
+}
+
+// GetConfig will return the config applied for this trigger.
+func (s *ExecTrigger) GetConfig() Config {
+	return s.config
+}
+
+// Execute will run the configured command, and wait for it to finish. The
+// given event is available in the templates of the command, args and env,
+// and is passed as json in the NIGHTSHIFT_EVENT environment variable. The
+// command succeeds if it exits with exit code 0.
+func (s *ExecTrigger) Execute(evt Event) error {
+	settings, err := ResolveSettings(s.config.Settings)
+	if err != nil {
+		return err
+	}
+	values := evt.Values(settings)
+	timeout, err := getDuration(settings["timeout"], "1m")
+	if err != nil {
+		return err
+	}
+	command, err := RenderTemplate(strings.TrimSpace(settings["command"]), values)
+	if err != nil {
+		return err
+	}
+	if command == "" {
+		return fmt.Errorf("no command specified")
+	}
+	args, err := renderLines(settings["args"], values)
+	if err != nil {
+		return err
+	}
+	env, err := renderLines(settings["env"], values)
+	if err != nil {
+		return err
+	}
+	for _, e := range env {
+		if !strings.Contains(e, "=") {
+			return fmt.Errorf("invalid env specified '%s'", e)
+		}
+	}
+	js, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(command, args...)
+	cmd.Dir = settings["workdir"]
+	cmd.Env = append(append(os.Environ(), "NIGHTSHIFT_EVENT="+string(js)), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err = <-done:
+	case <-timer.C:
+		if err := killProcessGroup(cmd); err != nil {
+			glog.Errorf("Error killing command %s: %s", command, err)
+		}
+		// don't wait for children that left the process group, and keep
+		// the output open
+		select {
+		case <-done:
+		case <-time.After(killWait):
+		}
+		return &ExecError{ExitCode: -1, timeout: true, err: fmt.Errorf("timeout after %s", timeout)}
+	}
+	glog.V(5).Infof("command: %s, stdout: %s, stderr: %s", command, stdout.String(), stderr.String())
+	if xerr, ok := err.(*exec.ExitError); ok {
+		return &ExecError{ExitCode: xerr.ExitCode(), Stderr: truncate(stderr.String(), maxOutput)}
+	}
+	return err
+}
+
+// renderLines will split the given text in lines, and render each non empty
+// line as a template with the given values.
+func renderLines(text string, values map[string]interface{}) ([]string, error) {
+	res := []string{}
+	for _, line := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		val, err := RenderTemplate(line, values)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, val)
+	}
+	return res, nil
+}
+
+// getDuration will parse the given duration, or the given default if no
+// duration is given.
+func getDuration(v, def string) (time.Duration, error) {
+	if strings.TrimSpace(v) == "" {
+		v = def
+	}
+	return time.ParseDuration(strings.TrimSpace(v))
+}
+
+// truncate will return the given text, limited to the given number of bytes.
+func truncate(text string, max int) string {
+	text = strings.TrimSpace(text)
+	if len(text) > max {
+		return text[:max] + "..."
+	}
+	return text
+}
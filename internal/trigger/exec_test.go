@@ -0,0 +1,126 @@
+package trigger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewExecTrigger(t *testing.T) {
+	if _, err := New("exec"); err != nil {
+		t.Errorf("failed test - could not instantiate exec module; %s", err)
+	}
+}
+
+func TestExecExecute(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nightshift")
+	if err != nil {
+		t.Fatalf("failed test - unable to create tempdir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	evt := Event{
+		ScannerId: "development",
+		Objects:   []EventObject{{Namespace: "development", Name: "shell"}},
+	}
+	tests := []struct {
+		settings map[string]string
+		output   string
+		exitcode int
+		timeout  bool
+		err      bool
+	}{
+		{
+			settings: map[string]string{
+				"command":  "/bin/sh",
+				"args":     "-c\necho \"$GREETING {{ .ScannerId }}{{ range .Objects }} {{ .Name }}{{ end }}\" > out",
+				"env":      "GREETING={{ .greeting }}",
+				"workdir":  dir,
+				"greeting": "hello",
+			},
+			output: "hello development shell",
+		},
+		{
+			settings: map[string]string{
+				"command": "/bin/sh",
+				"args":    "-c\necho \"$NIGHTSHIFT_EVENT\" > out",
+				"workdir": dir,
+			},
+			output: `"scanner_id":"development"`,
+		},
+		{
+			settings: map[string]string{
+				"command": "/bin/sh",
+				"args":    "-c\necho failed >&2; exit 3",
+			},
+			exitcode: 3,
+			err:      true,
+		},
+		{
+			settings: map[string]string{
+				"command": "/bin/sh",
+				"args":    "-c\nsleep 5",
+				"timeout": "100ms",
+			},
+			exitcode: -1,
+			timeout:  true,
+			err:      true,
+		},
+		{
+			settings: map[string]string{},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"command": "/non/existing/command"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"command": "/bin/true", "env": "INVALID"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"command": "/bin/true", "timeout": "soon"},
+			err:      true,
+		},
+	}
+	for i, tst := range tests {
+		os.Remove(filepath.Join(dir, "out"))
+		trgr := &ExecTrigger{}
+		trgr.SetConfig(Config{Settings: tst.settings})
+		start := time.Now()
+		err := trgr.Execute(evt)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if xerr, ok := err.(*ExecError); tst.exitcode != 0 {
+			if !ok || xerr.ExitCode != tst.exitcode || xerr.Timeout() != tst.timeout {
+				t.Errorf("failed test %d - expected exit code %d, got %v", i, tst.exitcode, err)
+			}
+		}
+		if tst.timeout && time.Since(start) > 2*time.Second {
+			t.Errorf("failed test %d - command was not stopped after timeout", i)
+		}
+		if tst.output != "" {
+			out, _ := ioutil.ReadFile(filepath.Join(dir, "out"))
+			if !strings.Contains(string(out), tst.output) {
+				t.Errorf("failed test %d - expected output %q, got %q", i, tst.output, out)
+			}
+		}
+	}
+}
+
+func TestExecRetryTimeout(t *testing.T) {
+	p := &RetryPolicy{RetryOn: []string{"timeout"}}
+	if !p.ShouldRetry(&ExecError{timeout: true}) {
+		t.Errorf("failed test - expected timeout of command to be retried")
+	}
+	if p.ShouldRetry(&ExecError{ExitCode: 1}) {
+		t.Errorf("failed test - expected failed command not to be retried")
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package trigger
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup will start the command in its own process group, so the
+// command and the children it started can be killed at once.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup will kill the process group of the given command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,14 @@
+package trigger
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on windows; only the command itself is killed
+// when it times out.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup will kill the given command.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,316 @@
+package trigger
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+// jobLabel is the label that is set on jobs created by a job trigger, with
+// the id of the trigger as value.
+const jobLabel = "joyrex2001.com/nightshift.trigger"
+
+// jobPollInterval is the interval in which the status of a job is checked
+// while waiting for it to complete.
+var jobPollInterval = 2 * time.Second
+
+// KubernetesGetter will return the rest config for the cluster with given
+// name, or the default cluster if no name is given.
+type KubernetesGetter func(cluster string) (*rest.Config, error)
+
+var kubernetesGetter KubernetesGetter = func(cluster string) (*rest.Config, error) {
+	return nil, fmt.Errorf("no kubernetes access")
+}
+
+// SetKubernetesGetter will set the function that is used by triggers to
+// connect to kubernetes.
+func SetKubernetesGetter(getter KubernetesGetter) {
+	kubernetesGetter = getter
+}
+
+// JobError is the error that is returned when a job failed, or did not
+// complete in time.
+type JobError struct {
+	Namespace string
+	Name      string
+	Reason    string
+	timeout   bool
+}
+
+// Error will return the error message, as required by the error interface.
+func (e *JobError) Error() string {
+	return fmt.Sprintf("error job %s/%s; %s", e.Namespace, e.Name, e.Reason)
+}
+
+// Timeout will return true if the job did not complete in time; this allows
+// retrying on timeouts.
+func (e *JobError) Timeout() bool {
+	return e.timeout
+}
+
+// Temporary will return true if the job did not complete in time.
+func (e *JobError) Temporary() bool {
+	return e.timeout
+}
+
+// JobTrigger is the object that implements triggers that create a kubernetes
+// batch/v1 job.
+type JobTrigger struct {
+	config Config
+}
+
+func init() {
+	RegisterModule("job", NewJobTrigger)
+}
+
+// NewJobTrigger will instantiate a new JobTrigger object.
+func NewJobTrigger() (Trigger, error) {
+	return &JobTrigger{config: Config{}}, nil
+}
+
+// SetConfig will set the generic configuration for this trigger.
+func (s *JobTrigger) SetConfig(cfg Config) {
+	s.config = cfg
+}
+
+// GetConfig will return the config applied for this trigger.
+func (s *JobTrigger) GetConfig() Config {
+	return s.config
+}
+
+// Execute will create the job as specified by the manifest in the settings,
+// or in the referred configmap. The manifest is a template, which has access
+// to the given event. If wait is enabled, it will wait until the job has
+// completed, and return an error if the job failed. Jobs that exceed the
+// history limit are removed afterwards.
+func (s *JobTrigger) Execute(evt Event) error {
+	settings, err := ResolveSettings(s.config.Settings)
+	if err != nil {
+		return err
+	}
+	kubernetes, err := kubernetesGetter(settings["cluster"])
+	if err != nil {
+		return err
+	}
+	core, err := scanner.NewRESTClient(kubernetes, corev1.SchemeGroupVersion, "/api")
+	if err != nil {
+		return err
+	}
+	batch, err := scanner.NewRESTClient(kubernetes, batchv1.SchemeGroupVersion, "/apis")
+	if err != nil {
+		return err
+	}
+	manifest, namespace, err := s.getManifest(core, settings)
+	if err != nil {
+		return err
+	}
+	rendered, err := RenderTemplate(manifest, evt.Values(settings))
+	if err != nil {
+		return err
+	}
+	job, err := s.newJob(rendered, namespace)
+	if err != nil {
+		return err
+	}
+
+	res := &batchv1.Job{}
+	err = batch.Post().
+		Namespace(job.Namespace).
+		Resource("jobs").
+		Body(job).
+		Do().
+		Into(res)
+	if err != nil {
+		return err
+	}
+	glog.V(4).Infof("Created job %s/%s", res.Namespace, res.Name)
+
+	if wait, _ := strconv.ParseBool(settings["wait"]); wait {
+		timeout, err := getDuration(settings["timeout"], "10m")
+		if err != nil {
+			return err
+		}
+		err = waitForJob(batch, res, timeout)
+		s.cleanupJobs(batch, res, settings["historylimit"])
+		return err
+	}
+	s.cleanupJobs(batch, res, settings["historylimit"])
+	return nil
+}
+
+// getManifest will return the manifest of the job; either as configured in
+// the manifest setting, or from the configmap setting, which refers to a
+// configmap as "name" or "namespace/name", and the key as configured in
+// configmapkey (default job.yaml). It will also return the namespace in which
+// the job should be created if the manifest does not specify one; this is the
+// namespace setting, or the namespace of the configmap.
+func (s *JobTrigger) getManifest(core *rest.RESTClient, settings map[string]string) (string, string, error) {
+	if m := settings["manifest"]; m != "" {
+		return m, settings["namespace"], nil
+	}
+	ref := strings.TrimSpace(settings["configmap"])
+	if ref == "" {
+		return "", "", fmt.Errorf("no manifest or configMap specified")
+	}
+	ns, name := settings["namespace"], ref
+	if i := strings.Index(ref, "/"); i >= 0 {
+		ns, name = ref[:i], ref[i+1:]
+	}
+	if ns == "" {
+		return "", "", fmt.Errorf("no namespace specified for configMap '%s'", ref)
+	}
+	key := settings["configmapkey"]
+	if key == "" {
+		key = "job.yaml"
+	}
+	cm := &corev1.ConfigMap{}
+	err := core.Get().
+		Namespace(ns).
+		Resource("configmaps").
+		Name(name).
+		Do().
+		Into(cm)
+	if err != nil {
+		return "", "", err
+	}
+	m, ok := cm.Data[key]
+	if !ok {
+		return "", "", fmt.Errorf("key %s not found in configmap %s/%s", key, ns, name)
+	}
+	return m, ns, nil
+}
+
+// newJob will parse the given yaml or json manifest, and return the job. The
+// namespace is set to the given namespace if the manifest does not contain
+// one. If the manifest has no name, a name is generated based on the trigger
+// id. The job is labeled with the trigger id, so old jobs can be cleaned up.
+func (s *JobTrigger) newJob(manifest, namespace string) (*batchv1.Job, error) {
+	job := &batchv1.Job{}
+	if err := yaml.Unmarshal([]byte(manifest), job); err != nil {
+		return nil, fmt.Errorf("invalid job manifest: %s", err)
+	}
+	if job.Kind != "" && job.Kind != "Job" {
+		return nil, fmt.Errorf("invalid job manifest: unexpected kind %s", job.Kind)
+	}
+	if job.Namespace == "" {
+		job.Namespace = namespace
+	}
+	if job.Namespace == "" {
+		return nil, fmt.Errorf("no namespace specified for job")
+	}
+	if job.Name == "" && job.GenerateName == "" {
+		job.GenerateName = s.config.Id + "-"
+	}
+	if job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	job.Labels[jobLabel] = s.config.Id
+	return job, nil
+}
+
+// waitForJob will wait until the given job has completed, failed, or the
+// timeout has passed.
+func waitForJob(batch *rest.RESTClient, job *batchv1.Job, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		cur := &batchv1.Job{}
+		err := batch.Get().
+			Namespace(job.Namespace).
+			Resource("jobs").
+			Name(job.Name).
+			Do().
+			Into(cur)
+		if err != nil {
+			return err
+		}
+		if done, err := jobFinished(cur); done {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return &JobError{Namespace: job.Namespace, Name: job.Name, Reason: "not completed in " + timeout.String(), timeout: true}
+		}
+		time.Sleep(jobPollInterval)
+	}
+}
+
+// jobFinished will return true if the given job has finished, together with
+// an error if the job failed.
+func jobFinished(job *batchv1.Job) (bool, error) {
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return true, &JobError{Namespace: job.Namespace, Name: job.Name, Reason: c.Reason + ": " + c.Message}
+		}
+	}
+	return false, nil
+}
+
+// cleanupJobs will remove the oldest finished jobs created by this trigger in
+// the namespace of the given job, keeping the number of finished jobs as
+// configured with historylimit. Jobs that are still running, and the given
+// job that has just been created, are never removed. If no history limit is
+// configured, jobs are not removed.
+func (s *JobTrigger) cleanupJobs(batch *rest.RESTClient, created *batchv1.Job, limit string) {
+	if strings.TrimSpace(limit) == "" {
+		return
+	}
+	keep, err := strconv.Atoi(strings.TrimSpace(limit))
+	if err != nil || keep < 0 {
+		glog.Errorf("Error cleaning up jobs: invalid historyLimit '%s'", limit)
+		return
+	}
+	namespace := created.Namespace
+	list := &batchv1.JobList{}
+	err = batch.Get().
+		Namespace(namespace).
+		Resource("jobs").
+		VersionedParams(&metav1.ListOptions{LabelSelector: jobLabel + "=" + s.config.Id}, scheme.ParameterCodec).
+		Do().
+		Into(list)
+	if err != nil {
+		glog.Errorf("Error cleaning up jobs: %s", err)
+		return
+	}
+	jobs := []batchv1.Job{}
+	for _, job := range list.Items {
+		if done, _ := jobFinished(&job); done {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[j].CreationTimestamp.Before(&jobs[i].CreationTimestamp)
+	})
+	policy := metav1.DeletePropagationBackground
+	for i := keep; i < len(jobs); i++ {
+		if jobs[i].Name == created.Name {
+			continue
+		}
+		err := batch.Delete().
+			Namespace(namespace).
+			Resource("jobs").
+			Name(jobs[i].Name).
+			Body(&metav1.DeleteOptions{PropagationPolicy: &policy}).
+			Do().
+			Error()
+		if err != nil {
+			glog.Errorf("Error removing job %s/%s: %s", namespace, jobs[i].Name, err)
+		}
+	}
+}
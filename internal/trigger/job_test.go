@@ -0,0 +1,230 @@
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"github.com/joyrex2001/nightshift/internal/scanner"
+)
+
+const testJobManifest = `apiVersion: batch/v1
+kind: Job
+metadata:
+  labels:
+    scanner: "{{ .ScannerId }}"
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: refresh
+        image: busybox
+        args: ["{{ .size }}"]
+`
+
+// fakeBatchAPI is a minimal kubernetes api server that supports creating,
+// getting, listing and deleting jobs, and getting configmaps.
+type fakeBatchAPI struct {
+	m         sync.Mutex
+	jobs      map[string]*batchv1.Job
+	created   int
+	condition batchv1.JobConditionType
+}
+
+func (f *fakeBatchAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.m.Lock()
+	defer f.m.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/api/v1/namespaces/ops/configmaps/refreshdb":
+		json.NewEncoder(w).Encode(&corev1.ConfigMap{
+			TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			Data:     map[string]string{"job.yaml": testJobManifest},
+		})
+	case len(path) == 6 && path[5] == "jobs" && r.Method == "POST":
+		job := &batchv1.Job{}
+		json.NewDecoder(r.Body).Decode(job)
+		f.created++
+		if job.Name == "" {
+			job.Name = fmt.Sprintf("%s%d", job.GenerateName, f.created)
+		}
+		job.CreationTimestamp = metav1.NewTime(time.Unix(int64(f.created), 0))
+		f.jobs[job.Name] = job
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(job)
+	case len(path) == 6 && path[5] == "jobs" && r.Method == "GET":
+		list := &batchv1.JobList{TypeMeta: metav1.TypeMeta{Kind: "JobList", APIVersion: "batch/v1"}}
+		for _, job := range f.jobs {
+			if r.URL.Query().Get("labelSelector") == jobLabel+"="+job.Labels[jobLabel] {
+				list.Items = append(list.Items, *job)
+			}
+		}
+		json.NewEncoder(w).Encode(list)
+	case len(path) == 7 && path[5] == "jobs" && r.Method == "GET":
+		job, ok := f.jobs[path[6]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if f.condition != "" {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: f.condition, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}}
+		}
+		json.NewEncoder(w).Encode(job)
+	case len(path) == 7 && path[5] == "jobs" && r.Method == "DELETE":
+		delete(f.jobs, path[6])
+		json.NewEncoder(w).Encode(&metav1.Status{Status: "Success"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestJobExecute(t *testing.T) {
+	api := &fakeBatchAPI{jobs: map[string]*batchv1.Job{}}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+	SetKubernetesGetter(func(cluster string) (*rest.Config, error) {
+		return &rest.Config{Host: srv.URL}, nil
+	})
+	jobPollInterval = 10 * time.Millisecond
+
+	tests := []struct {
+		settings  map[string]string
+		condition batchv1.JobConditionType
+		jobs      int
+		timeout   bool
+		err       bool
+	}{
+		{
+			settings: map[string]string{"namespace": "ops", "manifest": testJobManifest, "size": "small"},
+			jobs:     1,
+		},
+		{
+			settings: map[string]string{"namespace": "ops", "manifest": testJobManifest, "historylimit": "2"},
+			jobs:     2,
+		},
+		{
+			settings:  map[string]string{"namespace": "ops", "configmap": "refreshdb", "wait": "true", "historylimit": "2"},
+			condition: batchv1.JobComplete,
+			jobs:      3,
+		},
+		{
+			settings:  map[string]string{"configmap": "ops/refreshdb", "wait": "true", "historylimit": "1"},
+			condition: batchv1.JobFailed,
+			jobs:      3,
+			err:       true,
+		},
+		{
+			settings: map[string]string{"namespace": "ops", "manifest": testJobManifest, "wait": "true", "timeout": "50ms", "historylimit": "1"},
+			jobs:     4,
+			timeout:  true,
+			err:      true,
+		},
+		{
+			settings: map[string]string{"namespace": "ops", "manifest": testJobManifest, "historylimit": "0"},
+			jobs:     4,
+		},
+		{
+			settings: map[string]string{"namespace": "ops"},
+			jobs:     4,
+			err:      true,
+		},
+		{
+			settings: map[string]string{"namespace": "ops", "configmap": "missing"},
+			jobs:     4,
+			err:      true,
+		},
+		{
+			settings: map[string]string{"manifest": testJobManifest},
+			jobs:     4,
+			err:      true,
+		},
+		{
+			settings: map[string]string{"namespace": "ops", "manifest": "kind: Pod"},
+			jobs:     4,
+			err:      true,
+		},
+	}
+	for i, tst := range tests {
+		api.condition = tst.condition
+		trgr := &JobTrigger{}
+		trgr.SetConfig(Config{Id: "refreshdb", Settings: tst.settings})
+		err := trgr.Execute(Event{ScannerId: "development"})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if jerr, ok := err.(*JobError); tst.timeout && (!ok || !jerr.Timeout()) {
+			t.Errorf("failed test %d - expected timeout, got %v", i, err)
+		}
+		if len(api.jobs) != tst.jobs {
+			t.Errorf("failed test %d - expected %d jobs, got %d", i, tst.jobs, len(api.jobs))
+		}
+	}
+
+	for _, job := range api.jobs {
+		if job.Namespace != "ops" || job.Labels[jobLabel] != "refreshdb" || job.Labels["scanner"] != "development" {
+			t.Errorf("failed test - unexpected job metadata %v", job.ObjectMeta)
+		}
+		if !strings.HasPrefix(job.Name, "refreshdb-") {
+			t.Errorf("failed test - expected generated job name, got %s", job.Name)
+		}
+	}
+}
+
+func TestCleanupJobs(t *testing.T) {
+	job := func(name string, created int64, condition batchv1.JobConditionType) *batchv1.Job {
+		job := &batchv1.Job{}
+		job.Name = name
+		job.Namespace = "ops"
+		job.Labels = map[string]string{jobLabel: "refreshdb"}
+		job.CreationTimestamp = metav1.NewTime(time.Unix(created, 0))
+		if condition != "" {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}}
+		}
+		return job
+	}
+	api := &fakeBatchAPI{jobs: map[string]*batchv1.Job{
+		"completed": job("completed", 1, batchv1.JobComplete),
+		"failed":    job("failed", 2, batchv1.JobFailed),
+		"running":   job("running", 3, ""),
+		"created":   job("created", 4, ""),
+	}}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+	batch, err := scanner.NewRESTClient(&rest.Config{Host: srv.URL}, batchv1.SchemeGroupVersion, "/apis")
+	if err != nil {
+		t.Fatalf("failed test - unexpected err: %s", err)
+	}
+
+	trgr := &JobTrigger{}
+	trgr.SetConfig(Config{Id: "refreshdb"})
+	trgr.cleanupJobs(batch, api.jobs["created"], "1")
+	if _, ok := api.jobs["completed"]; ok || len(api.jobs) != 3 {
+		t.Errorf("failed test - expected the oldest finished job to be removed, got %v", api.jobs)
+	}
+	trgr.cleanupJobs(batch, api.jobs["created"], "0")
+	if _, ok := api.jobs["failed"]; ok || len(api.jobs) != 2 || api.jobs["running"] == nil || api.jobs["created"] == nil {
+		t.Errorf("failed test - expected the running and created jobs to be kept, got %v", api.jobs)
+	}
+
+	// a created job that has finished already is kept as well
+	api.jobs["created"] = job("created", 4, batchv1.JobComplete)
+	trgr.cleanupJobs(batch, api.jobs["created"], "0")
+	if len(api.jobs) != 2 {
+		t.Errorf("failed test - expected the created job to be kept, got %v", api.jobs)
+	}
+}
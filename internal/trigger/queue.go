@@ -0,0 +1,56 @@
+package trigger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// OverflowDrop will discard new executions when the queue is full.
+	OverflowDrop = "drop"
+	// OverflowCoalesce will merge new executions with the last queued
+	// execution when the queue is full.
+	OverflowCoalesce = "coalesce"
+	// OverflowReject will discard new executions when the queue is full, and
+	// record them as failed executions.
+	OverflowReject = "reject"
+)
+
+// QueuePolicy describes how many executions of a trigger can run at the same
+// time, how many executions can be queued, and what happens with new
+// executions if the queue is full. It is configured with the concurrency,
+// queueSize and overflow settings of a trigger.
+type QueuePolicy struct {
+	Concurrency int
+	QueueSize   int
+	Overflow    string
+}
+
+// NewQueuePolicy will return the queue policy as configured in the given
+// settings. By default, a trigger is executed once at a time, 10 executions
+// can be queued, and new executions are coalesced with the last queued
+// execution if the queue is full.
+func NewQueuePolicy(settings map[string]string) (*QueuePolicy, error) {
+	var err error
+	p := &QueuePolicy{Concurrency: 1, QueueSize: 10, Overflow: OverflowCoalesce}
+	if v := strings.TrimSpace(settings["concurrency"]); v != "" {
+		if p.Concurrency, err = strconv.Atoi(v); err != nil || p.Concurrency < 1 {
+			return nil, fmt.Errorf("invalid concurrency '%s'", v)
+		}
+	}
+	if v := strings.TrimSpace(settings["queuesize"]); v != "" {
+		if p.QueueSize, err = strconv.Atoi(v); err != nil || p.QueueSize < 1 {
+			return nil, fmt.Errorf("invalid queueSize '%s'", v)
+		}
+	}
+	if v := strings.ToLower(strings.TrimSpace(settings["overflow"])); v != "" {
+		switch v {
+		case OverflowDrop, OverflowCoalesce, OverflowReject:
+			p.Overflow = v
+		default:
+			return nil, fmt.Errorf("invalid overflow '%s'", v)
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,48 @@
+package trigger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewQueuePolicy(t *testing.T) {
+	tests := []struct {
+		settings map[string]string
+		policy   QueuePolicy
+		err      bool
+	}{
+		{
+			settings: map[string]string{},
+			policy:   QueuePolicy{Concurrency: 1, QueueSize: 10, Overflow: OverflowCoalesce},
+		},
+		{
+			settings: map[string]string{"concurrency": "3", "queuesize": "2", "overflow": "Reject"},
+			policy:   QueuePolicy{Concurrency: 3, QueueSize: 2, Overflow: OverflowReject},
+		},
+		{
+			settings: map[string]string{"overflow": "drop"},
+			policy:   QueuePolicy{Concurrency: 1, QueueSize: 10, Overflow: OverflowDrop},
+		},
+		{
+			settings: map[string]string{"concurrency": "0"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"queuesize": "few"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"overflow": "block"},
+			err:      true,
+		},
+	}
+	for i, tst := range tests {
+		p, err := NewQueuePolicy(tst.settings)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error: %v", i, err)
+		}
+		if err == nil && !reflect.DeepEqual(*p, tst.policy) {
+			t.Errorf("failed test %d - expected %#v, got %#v", i, tst.policy, *p)
+		}
+	}
+}
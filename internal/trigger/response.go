@@ -0,0 +1,72 @@
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// checkResponse will verify the body of the response against the configured
+// responseregex, and responsejsonpath and responsejsonvalue settings. It will
+// return an error if the body does not match.
+func (s *WebhookTrigger) checkResponse(body []byte) error {
+	if expr := s.config.Settings["responseregex"]; expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid responseRegex '%s': %s", expr, err)
+		}
+		if !re.Match(body) {
+			return fmt.Errorf("response does not match '%s'", expr)
+		}
+	}
+	if path := s.config.Settings["responsejsonpath"]; path != "" {
+		var doc interface{}
+		if err := json.Unmarshal(body, &doc); err != nil {
+			return fmt.Errorf("invalid json response: %s", err)
+		}
+		val, ok := lookupJSONPath(doc, path)
+		if !ok {
+			return fmt.Errorf("response does not contain '%s'", path)
+		}
+		exp, set := s.config.Settings["responsejsonvalue"]
+		if set && fmt.Sprint(val) != exp {
+			return fmt.Errorf("response '%s' is '%v', expected '%s'", path, val, exp)
+		}
+	}
+	return nil
+}
+
+// lookupJSONPath will return the value at the given path in the decoded json
+// document. The path is a dot separated list of keys and array indices, with
+// an optional leading $, e.g. "$.items[0].status" or "items.0.status". It
+// will return false if the path does not exist.
+func lookupJSONPath(doc interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.Replace(path, "[", ".", -1)
+	path = strings.Replace(path, "]", "", -1)
+	cur := doc
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			val, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			cur = val
+		case []interface{}:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(node) {
+				return nil, false
+			}
+			cur = node[idx]
+		default:
+			return nil, false
+		}
+	}
+	return cur, cur != nil
+}
@@ -0,0 +1,67 @@
+package trigger
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestLookupJSONPath(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal([]byte(`{"status":"ok","count":3,"items":[{"name":"a"},{"name":"b"}],"empty":null}`), &doc)
+	tests := []struct {
+		path  string
+		value interface{}
+		found bool
+	}{
+		{path: "status", value: "ok", found: true},
+		{path: "$.status", value: "ok", found: true},
+		{path: "count", value: float64(3), found: true},
+		{path: "items[1].name", value: "b", found: true},
+		{path: "$.items.0.name", value: "a", found: true},
+		{path: "items[2].name", found: false},
+		{path: "status.name", found: false},
+		{path: "missing", found: false},
+		{path: "empty", found: false},
+	}
+	for i, tst := range tests {
+		val, found := lookupJSONPath(doc, tst.path)
+		if found != tst.found {
+			t.Errorf("failed test %d - expected found %v, got %v", i, tst.found, found)
+		}
+		if found && !reflect.DeepEqual(val, tst.value) {
+			t.Errorf("failed test %d - expected %#v, got %#v", i, tst.value, val)
+		}
+	}
+}
+
+func TestCheckResponse(t *testing.T) {
+	body := []byte(`{"status":"ok","build":{"id":42}}`)
+	tests := []struct {
+		settings map[string]string
+		err      bool
+	}{
+		{settings: map[string]string{}, err: false},
+		{settings: map[string]string{"responseregex": `"status":\s*"ok"`}, err: false},
+		{settings: map[string]string{"responseregex": `"status":\s*"failed"`}, err: true},
+		{settings: map[string]string{"responseregex": `(`}, err: true},
+		{settings: map[string]string{"responsejsonpath": "build.id"}, err: false},
+		{settings: map[string]string{"responsejsonpath": "build.id", "responsejsonvalue": "42"}, err: false},
+		{settings: map[string]string{"responsejsonpath": "status", "responsejsonvalue": "failed"}, err: true},
+		{settings: map[string]string{"responsejsonpath": "build.name"}, err: true},
+	}
+	for i, tst := range tests {
+		wht := &WebhookTrigger{config: Config{Settings: tst.settings}}
+		err := wht.checkResponse(body)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+	}
+	wht := &WebhookTrigger{config: Config{Settings: map[string]string{"responsejsonpath": "status"}}}
+	if err := wht.checkResponse([]byte("not json")); err == nil {
+		t.Errorf("failed test - expected err for invalid json, but got none")
+	}
+}
@@ -0,0 +1,104 @@
+package trigger
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// RetryPolicy describes how often, and on which errors, the execution of a
+// trigger is retried. It is configured with the retries, backoff and retryOn
+// settings of a trigger.
+type RetryPolicy struct {
+	Retries int
+	Backoff time.Duration
+	RetryOn []string
+}
+
+// NewRetryPolicy will return the retry policy as configured in the given
+// settings. If no retries are configured, the trigger will be executed just
+// once. The backoff defaults to 1s, and is doubled after each attempt. The
+// retryOn setting is a comma separated list of status codes (e.g. 503),
+// status code classes (e.g. 5xx), "timeout" and "error" (any error that is
+// not a status code). If retryOn is not set, all errors are retried.
+func NewRetryPolicy(settings map[string]string) (*RetryPolicy, error) {
+	var err error
+	p := &RetryPolicy{Backoff: time.Second, RetryOn: []string{}}
+	if v := strings.TrimSpace(settings["retries"]); v != "" {
+		if p.Retries, err = strconv.Atoi(v); err != nil || p.Retries < 0 {
+			return nil, fmt.Errorf("invalid retries '%s'", v)
+		}
+	}
+	if v := strings.TrimSpace(settings["backoff"]); v != "" {
+		if p.Backoff, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("invalid backoff '%s': %s", v, err)
+		}
+	}
+	for _, v := range strings.Split(settings["retryon"], ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v != "" {
+			p.RetryOn = append(p.RetryOn, v)
+		}
+	}
+	return p, nil
+}
+
+// ShouldRetry will return true if the given error should be retried
+// according to this policy.
+func (p *RetryPolicy) ShouldRetry(err error) bool {
+	if err == nil {
+		return false
+	}
+	if len(p.RetryOn) == 0 {
+		return true
+	}
+	serr, isStatus := err.(*StatusError)
+	nerr, isNet := err.(net.Error)
+	for _, on := range p.RetryOn {
+		switch {
+		case on == "timeout":
+			if isNet && nerr.Timeout() {
+				return true
+			}
+		case on == "error":
+			if !isStatus {
+				return true
+			}
+		case isStatus && len(on) == 3 && strings.HasSuffix(on, "xx"):
+			if strconv.Itoa(serr.StatusCode)[0] == on[0] {
+				return true
+			}
+		case isStatus:
+			if on == strconv.Itoa(serr.StatusCode) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExecuteWithRetry will execute the given trigger with given event, and will
+// retry the execution according to the retry policy of the trigger. It will
+// return the number of attempts, and the error of the last attempt.
+func ExecuteWithRetry(trgr Trigger, evt Event) (int, error) {
+	p, err := NewRetryPolicy(trgr.GetConfig().Settings)
+	if err != nil {
+		return 0, err
+	}
+	backoff := p.Backoff
+	attempt := 0
+	for {
+		attempt++
+		err = trgr.Execute(evt)
+		if attempt > p.Retries || !p.ShouldRetry(err) {
+			return attempt, err
+		}
+		glog.Warningf("Trigger %s failed (attempt %d), retrying in %s: %s", trgr.GetConfig().Id, attempt, backoff, err)
+		time.Sleep(backoff)
+		backoff += backoff
+	}
+}
@@ -0,0 +1,126 @@
+package trigger
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (e *timeoutError) Error() string   { return "timeout" }
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+// flaky is a trigger that will fail with the given errors, before it succeeds.
+type flaky struct {
+	cfg   Config
+	errs  []error
+	calls int
+}
+
+func (f *flaky) SetConfig(c Config) { f.cfg = c }
+func (f *flaky) GetConfig() Config  { return f.cfg }
+func (f *flaky) Execute(evt Event) error {
+	f.calls++
+	if f.calls <= len(f.errs) {
+		return f.errs[f.calls-1]
+	}
+	return nil
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	tests := []struct {
+		settings map[string]string
+		policy   RetryPolicy
+		err      bool
+	}{
+		{
+			settings: map[string]string{},
+			policy:   RetryPolicy{Retries: 0, Backoff: time.Second, RetryOn: []string{}},
+		},
+		{
+			settings: map[string]string{"retries": "3", "backoff": "5s", "retryon": "503, 5XX,timeout"},
+			policy:   RetryPolicy{Retries: 3, Backoff: 5 * time.Second, RetryOn: []string{"503", "5xx", "timeout"}},
+		},
+		{
+			settings: map[string]string{"retries": "many"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"retries": "-1"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"backoff": "5"},
+			err:      true,
+		},
+	}
+	for i, tst := range tests {
+		p, err := NewRetryPolicy(tst.settings)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error result: %v", i, err)
+			continue
+		}
+		if err == nil && (p.Retries != tst.policy.Retries || p.Backoff != tst.policy.Backoff ||
+			len(p.RetryOn) != len(tst.policy.RetryOn)) {
+			t.Errorf("failed test %d - expected %v, got %v", i, tst.policy, *p)
+		}
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	status := func(code int) error { return &StatusError{StatusCode: code} }
+	tests := []struct {
+		retryOn []string
+		err     error
+		retry   bool
+	}{
+		{retryOn: []string{}, err: nil, retry: false},
+		{retryOn: []string{}, err: status(500), retry: true},
+		{retryOn: []string{}, err: errors.New("refused"), retry: true},
+		{retryOn: []string{"503"}, err: status(503), retry: true},
+		{retryOn: []string{"503"}, err: status(502), retry: false},
+		{retryOn: []string{"5xx"}, err: status(502), retry: true},
+		{retryOn: []string{"5xx"}, err: status(404), retry: false},
+		{retryOn: []string{"5xx"}, err: errors.New("refused"), retry: false},
+		{retryOn: []string{"timeout"}, err: &timeoutError{}, retry: true},
+		{retryOn: []string{"timeout"}, err: errors.New("refused"), retry: false},
+		{retryOn: []string{"error"}, err: errors.New("refused"), retry: true},
+		{retryOn: []string{"error"}, err: status(500), retry: false},
+	}
+	for i, tst := range tests {
+		p := &RetryPolicy{RetryOn: tst.retryOn}
+		if res := p.ShouldRetry(tst.err); res != tst.retry {
+			t.Errorf("failed test %d - expected %t, got %t", i, tst.retry, res)
+		}
+	}
+}
+
+func TestExecuteWithRetry(t *testing.T) {
+	fail := &StatusError{StatusCode: 503}
+	tests := []struct {
+		settings map[string]string
+		errs     []error
+		attempts int
+		err      bool
+	}{
+		{settings: map[string]string{}, errs: []error{}, attempts: 1, err: false},
+		{settings: map[string]string{}, errs: []error{fail}, attempts: 1, err: true},
+		{settings: map[string]string{"retries": "2", "backoff": "1ms"}, errs: []error{fail}, attempts: 2, err: false},
+		{settings: map[string]string{"retries": "2", "backoff": "1ms"}, errs: []error{fail, fail, fail}, attempts: 3, err: true},
+		{settings: map[string]string{"retries": "2", "backoff": "1ms", "retryon": "502"}, errs: []error{fail}, attempts: 1, err: true},
+		{settings: map[string]string{"retries": "x"}, errs: []error{}, attempts: 0, err: true},
+	}
+	for i, tst := range tests {
+		trgr := &flaky{errs: tst.errs}
+		trgr.SetConfig(Config{Id: "flaky", Settings: tst.settings})
+		attempts, err := ExecuteWithRetry(trgr, Event{})
+		if attempts != tst.attempts {
+			t.Errorf("failed test %d - expected %d attempts, got %d", i, tst.attempts, attempts)
+		}
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error result: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package trigger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+)
+
+const (
+	// secretRefPrefix is the prefix of setting values that refer to a key in
+	// a kubernetes secret, e.g. "secretRef:namespace/name/key".
+	secretRefPrefix = "secretRef:"
+	// fileRefPrefix is the prefix of setting values that refer to a file,
+	// e.g. "fileRef:/etc/nightshift/token".
+	fileRefPrefix = "fileRef:"
+	// redacted is the value that is shown instead of sensitive settings.
+	redacted = "********"
+)
+
+// SecretGetter will return the value of given key in the kubernetes secret
+// with given name, in given namespace.
+type SecretGetter func(namespace, name, key string) (string, error)
+
+var secretGetter SecretGetter = func(namespace, name, key string) (string, error) {
+	return "", fmt.Errorf("unable to read secret %s/%s: no kubernetes access", namespace, name)
+}
+
+// SetSecretGetter will set the function that is used to read kubernetes
+// secrets that are referred to in trigger settings and templates.
+func SetSecretGetter(getter SecretGetter) {
+	secretGetter = getter
+}
+
+// ResolveSettings will return a copy of given settings in which all values
+// that refer to a secret or a file are replaced with the actual value. It
+// will return an error if a reference can not be resolved.
+func ResolveSettings(settings map[string]string) (map[string]string, error) {
+	res := map[string]string{}
+	for k, v := range settings {
+		val, err := resolveValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving setting %s: %s", k, err)
+		}
+		res[k] = val
+	}
+	return res, nil
+}
+
+// resolveValue will return the value that is referred to by given setting
+// value, or the value itself if it is not a reference.
+func resolveValue(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, secretRefPrefix):
+		ref := strings.TrimSpace(strings.TrimPrefix(v, secretRefPrefix))
+		i := strings.LastIndex(ref, "/")
+		if i < 0 {
+			return "", fmt.Errorf("invalid secret reference '%s', expected namespace/name/key", ref)
+		}
+		return templateSecret(ref[:i], ref[i+1:])
+	case strings.HasPrefix(v, fileRefPrefix):
+		return templateFile(strings.TrimSpace(strings.TrimPrefix(v, fileRefPrefix)))
+	}
+	return v, nil
+}
+
+// templateSecret implements the {{ secret "namespace/name" "key" }} method
+// which will return the value of the given key in the given secret.
+func templateSecret(ref, key string) (string, error) {
+	flds := strings.Split(ref, "/")
+	if len(flds) != 2 || flds[0] == "" || flds[1] == "" || key == "" {
+		return "", fmt.Errorf("invalid secret reference '%s/%s', expected namespace/name/key", ref, key)
+	}
+	return secretGetter(flds[0], flds[1], key)
+}
+
+// templateFile implements the {{ file "/path" }} method which will return the
+// contents of the given file, without trailing newlines.
+func templateFile(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("no file specified")
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// Redacted will return a copy of the config in which the values of sensitive
+// settings (e.g. tokens, passwords and secrets) are redacted. The values of
+// sensitive headers (e.g. Authorization) in the headers setting are redacted
+// as well, and credentials are removed from urls. References to secrets and
+// files are kept, as they do not contain the actual value.
+func (c Config) Redacted() Config {
+	settings := map[string]string{}
+	for k, v := range c.Settings {
+		switch {
+		case strings.HasPrefix(v, secretRefPrefix) || strings.HasPrefix(v, fileRefPrefix):
+		case isSensitive(k):
+			v = redacted
+		case k == "headers":
+			v = redactHeaders(v)
+		}
+		settings[k] = userinfoRegex.ReplaceAllString(v, "${1}")
+	}
+	c.Settings = settings
+	return c
+}
+
+// userinfoRegex matches the scheme and userinfo (user:password@) of urls.
+var userinfoRegex = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/@\s]+@`)
+
+// isSensitive will return true if the setting with given (lowercase) key
+// contains a sensitive value.
+func isSensitive(key string) bool {
+	for _, s := range []string{"secret", "token", "password"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSensitiveHeader will return true if the header with given name contains
+// a sensitive value.
+func isSensitiveHeader(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, s := range []string{"authorization", "cookie", "key", "auth"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return isSensitive(name)
+}
+
+// redactHeaders will redact the values of the sensitive headers in given
+// headers setting, which contains a header per line as name: value.
+func redactHeaders(headers string) string {
+	lines := strings.Split(strings.Replace(headers, "\r\n", "\n", -1), "\n")
+	for i, line := range lines {
+		flds := strings.SplitN(line, ":", 2)
+		if len(flds) == 2 && isSensitiveHeader(flds[0]) {
+			lines[i] = flds[0] + ": " + redacted
+		}
+	}
+	return strings.Join(lines, "\n")
+}
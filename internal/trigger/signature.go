@@ -0,0 +1,73 @@
+package trigger
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSignatureHeader = "X-Nightshift-Signature"
+	defaultTimestampHeader = "X-Nightshift-Timestamp"
+)
+
+// algorithms contains the supported hash algorithms for signing webhooks.
+var algorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// sign will add the timestamp and signature headers to the given request, if
+// a signingsecret is configured. The signature is the hex encoded hmac of the
+// timestamp (unix seconds), a dot, and the payload, prefixed with the name of
+// the algorithm; e.g. "sha256=5257a869...".
+func (s *WebhookTrigger) sign(req *http.Request, payload []byte, now time.Time) error {
+	secret := s.config.Settings["signingsecret"]
+	if secret == "" {
+		return nil
+	}
+	algo := strings.ToLower(strings.TrimSpace(s.config.Settings["signingalgorithm"]))
+	if algo == "" {
+		algo = "sha256"
+	}
+	if _, ok := algorithms[algo]; !ok {
+		return fmt.Errorf("invalid signingAlgorithm '%s'", algo)
+	}
+	ts := strconv.FormatInt(now.Unix(), 10)
+	req.Header.Set(s.getSetting("timestampheader", defaultTimestampHeader), ts)
+	req.Header.Set(s.getSetting("signatureheader", defaultSignatureHeader), algo+"="+Signature(algo, secret, ts, payload))
+	return nil
+}
+
+// Signature will return the hex encoded hmac of given timestamp and payload,
+// with given algorithm and secret, as sent by webhooks with a signingsecret.
+// Receivers can use this to verify the signature.
+func Signature(algo, secret, timestamp string, payload []byte) string {
+	newHash, ok := algorithms[algo]
+	if !ok {
+		return ""
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// getSetting will return the trimmed value of given setting, or the given
+// default value if the setting is not configured.
+func (s *WebhookTrigger) getSetting(key, def string) string {
+	if v := strings.TrimSpace(s.config.Settings[key]); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,108 @@
+package trigger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		algo string
+		sig  string
+	}{
+		{algo: "sha1", sig: "5b6232a6b3f8414e55ab8a48b92f5f5584d4772b"},
+		{algo: "sha256", sig: "852baf3015f7145b8539e21fc93dc0ebfb95cf6d294367d8fdfe1839a6f809fd"},
+		{algo: "sha512", sig: "ca705ec1288527f2006fc85fdaf04f7b6c35f1519d2b49624888e352607aab9705f3f13d20b628066a20ce55d7ba57a6b492a4607b0071c6d571605bc84e0f6b"},
+		{algo: "md5", sig: ""},
+	}
+	for i, tst := range tests {
+		sig := Signature(tst.algo, "secret", "1551722400", []byte(`{"a":1}`))
+		if sig != tst.sig {
+			t.Errorf("failed test %d - expected %s, got %s", i, tst.sig, sig)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	now := time.Unix(1551722400, 0)
+	payload := []byte(`{"a":1}`)
+	tests := []struct {
+		settings map[string]string
+		headers  map[string]string
+		err      bool
+	}{
+		{
+			settings: map[string]string{},
+			headers:  map[string]string{defaultSignatureHeader: "", defaultTimestampHeader: ""},
+		},
+		{
+			settings: map[string]string{"signingsecret": "secret"},
+			headers: map[string]string{
+				defaultTimestampHeader: "1551722400",
+				defaultSignatureHeader: "sha256=852baf3015f7145b8539e21fc93dc0ebfb95cf6d294367d8fdfe1839a6f809fd",
+			},
+		},
+		{
+			settings: map[string]string{
+				"signingsecret":    "secret",
+				"signingalgorithm": "SHA1",
+				"signatureheader":  "X-Hub-Signature",
+				"timestampheader":  "X-Hub-Timestamp",
+			},
+			headers: map[string]string{
+				"X-Hub-Timestamp": "1551722400",
+				"X-Hub-Signature": "sha1=5b6232a6b3f8414e55ab8a48b92f5f5584d4772b",
+			},
+		},
+		{
+			settings: map[string]string{"signingsecret": "secret", "signingalgorithm": "md5"},
+			err:      true,
+		},
+	}
+	for i, tst := range tests {
+		wht := &WebhookTrigger{config: Config{Settings: tst.settings}}
+		req, _ := http.NewRequest("POST", "http://localhost", nil)
+		err := wht.sign(req, payload, now)
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		for k, v := range tst.headers {
+			if h := req.Header.Get(k); h != v {
+				t.Errorf("failed test %d - expected header %s '%s', got '%s'", i, k, v, h)
+			}
+		}
+	}
+}
+
+func TestExecuteSigned(t *testing.T) {
+	var body []byte
+	var ts, sig string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		ts = r.Header.Get(defaultTimestampHeader)
+		sig = r.Header.Get(defaultSignatureHeader)
+	}))
+	defer srv.Close()
+
+	wht := &WebhookTrigger{}
+	wht.SetConfig(Config{Settings: map[string]string{
+		"url":           srv.URL,
+		"body":          `{"scanner":"{{ .ScannerId }}"}`,
+		"signingsecret": "secret",
+	}})
+	if err := wht.Execute(Event{ScannerId: "development"}); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if string(body) != `{"scanner":"development"}` {
+		t.Errorf("failed test - unexpected body %s", body)
+	}
+	if exp := "sha256=" + Signature("sha256", "secret", ts, body); ts == "" || sig != exp {
+		t.Errorf("failed test - expected signature %s, got %s", exp, sig)
+	}
+}
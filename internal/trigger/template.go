@@ -16,10 +16,12 @@ import (
 // rendering of the template fails.
 func RenderTemplate(templ string, values interface{}) (string, error) {
 	var funcs = template.FuncMap{
-		"env":  templateEnv,
-		"add":  templateAdd,
-		"now":  templateNow,
-		"time": templateTime,
+		"env":    templateEnv,
+		"add":    templateAdd,
+		"now":    templateNow,
+		"time":   templateTime,
+		"secret": templateSecret,
+		"file":   templateFile,
 	}
 	// render template
 	tobj, err := template.New("template").Funcs(funcs).Parse(templ)
@@ -3,21 +3,36 @@ package trigger
 import (
 	"fmt"
 	"strings"
+
+	"github.com/joyrex2001/nightshift/internal/schedule"
 )
 
 // Trigger defines the public interface of trigger modules.
 type Trigger interface {
 	SetConfig(Config)
 	GetConfig() Config
-	Execute() error
+	Execute(Event) error
+}
+
+// Waker is implemented by triggers that should be notified when objects are
+// woken up manually, outside of their schedule; e.g. to undo what has been
+// done when the objects were scaled down. Besides the woken objects, the
+// objects in the same namespaces that are still scaled down are given, so
+// what has been done for a namespace as a whole is only undone when the
+// namespace is awake.
+type Waker interface {
+	Wake(woken, asleep []EventObject) error
 }
 
 // Config is the configuration for this trigger, and contains a hashmap with
-// generic settings. The key for each value should be lowercased always.
+// generic settings. The key for each value should be lowercased always. The
+// optional schedule will execute the trigger on its own, without any objects
+// being scaled.
 type Config struct {
-	Id       string            `json:"id"`
-	Type     string            `json:"type"`
-	Settings map[string]string `json:"settings"`
+	Id       string               `json:"id"`
+	Type     string               `json:"type"`
+	Settings map[string]string    `json:"settings"`
+	Schedule []*schedule.Schedule `json:"schedule,omitempty"`
 }
 
 // Factory is the factory method for a trigger implementation module.
@@ -20,7 +20,7 @@ func (m *mock) GetConfig() Config {
 	return m.cfg
 }
 
-func (m *mock) Execute() error {
+func (m *mock) Execute(evt Event) error {
 	return nil
 }
 
@@ -62,3 +62,42 @@ func TestNew(t *testing.T) {
 		}
 	}
 }
+
+func TestEventValues(t *testing.T) {
+	tests := []struct {
+		in     string
+		out    string
+		params map[string]string
+	}{
+		{
+			in:  `{{ .db }}-{{ .size }}`,
+			out: `main-large`,
+		},
+		{
+			in:     `{{ .db }}-{{ .size }}`,
+			out:    `main-large`,
+			params: map[string]string{"db": "orders", "url": "http://evil"},
+		},
+		{
+			in:     `{{ or .Params.db .db }}-{{ or .Params.size .size }}`,
+			out:    `orders-large`,
+			params: map[string]string{"db": "orders"},
+		},
+		{
+			in:     `{{ .Params.db }}-{{ .Params.size }}-{{ .Settings.db }}`,
+			out:    `orders-small-main`,
+			params: map[string]string{"db": "orders", "size": "small"},
+		},
+	}
+	settings := map[string]string{"db": "main", "size": "large"}
+	for i, tst := range tests {
+		evt := Event{Params: tst.params}
+		out, err := RenderTemplate(tst.in, evt.Values(settings))
+		if err != nil {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if out != tst.out {
+			t.Errorf("failed test %d - expected %s, got %s", i, tst.out, out)
+		}
+	}
+}
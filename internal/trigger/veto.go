@@ -0,0 +1,58 @@
+package trigger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// VetoSkip will skip a scale event that is vetoed by a before hook.
+	VetoSkip = "skip"
+	// VetoRetry will retry a scale event that is vetoed by a before hook
+	// after a delay, until the number of retries is exhausted.
+	VetoRetry = "retry"
+)
+
+// VetoPolicy describes what happens with a scale event if the trigger, used
+// as before hook, fails and thereby vetoes the event. It is configured with
+// the onVeto, vetoRetries and vetoDelay settings of a trigger.
+type VetoPolicy struct {
+	OnVeto  string
+	Retries int
+	Delay   time.Duration
+}
+
+// NewVetoPolicy will return the veto policy as configured in the given
+// settings. By default, a vetoed event is skipped. If vetoed events are
+// retried, they are retried 3 times by default, 5 minutes apart.
+func NewVetoPolicy(settings map[string]string) (*VetoPolicy, error) {
+	var err error
+	p := &VetoPolicy{OnVeto: VetoSkip, Retries: 3, Delay: 5 * time.Minute}
+	if v := strings.ToLower(strings.TrimSpace(settings["onveto"])); v != "" {
+		switch v {
+		case VetoSkip, VetoRetry:
+			p.OnVeto = v
+		default:
+			return nil, fmt.Errorf("invalid onVeto '%s'", v)
+		}
+	}
+	if v := strings.TrimSpace(settings["vetoretries"]); v != "" {
+		if p.Retries, err = strconv.Atoi(v); err != nil || p.Retries < 0 {
+			return nil, fmt.Errorf("invalid vetoRetries '%s'", v)
+		}
+	}
+	if v := strings.TrimSpace(settings["vetodelay"]); v != "" {
+		if p.Delay, err = time.ParseDuration(v); err != nil || p.Delay <= 0 {
+			return nil, fmt.Errorf("invalid vetoDelay '%s'", v)
+		}
+	}
+	return p, nil
+}
+
+// ShouldRetry will return true if a vetoed event, that has been retried the
+// given number of times already, should be retried again.
+func (p *VetoPolicy) ShouldRetry(retries int) bool {
+	return p.OnVeto == VetoRetry && retries < p.Retries
+}
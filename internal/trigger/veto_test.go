@@ -0,0 +1,61 @@
+package trigger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewVetoPolicy(t *testing.T) {
+	tests := []struct {
+		settings map[string]string
+		policy   VetoPolicy
+		retries  []bool
+		err      bool
+	}{
+		{
+			settings: map[string]string{},
+			policy:   VetoPolicy{OnVeto: VetoSkip, Retries: 3, Delay: 5 * time.Minute},
+			retries:  []bool{false},
+		},
+		{
+			settings: map[string]string{"onveto": "Retry", "vetoretries": "2", "vetodelay": "1m"},
+			policy:   VetoPolicy{OnVeto: VetoRetry, Retries: 2, Delay: time.Minute},
+			retries:  []bool{true, true, false},
+		},
+		{
+			settings: map[string]string{"onveto": "retry", "vetoretries": "0"},
+			policy:   VetoPolicy{OnVeto: VetoRetry, Retries: 0, Delay: 5 * time.Minute},
+			retries:  []bool{false},
+		},
+		{
+			settings: map[string]string{"onveto": "wait"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"vetoretries": "-1"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"vetodelay": "0s"},
+			err:      true,
+		},
+	}
+	for i, tst := range tests {
+		p, err := NewVetoPolicy(tst.settings)
+		if (err != nil) != tst.err {
+			t.Errorf("failed test %d - unexpected error: %v", i, err)
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(*p, tst.policy) {
+			t.Errorf("failed test %d - expected %#v, got %#v", i, tst.policy, *p)
+		}
+		for n, exp := range tst.retries {
+			if p.ShouldRetry(n) != exp {
+				t.Errorf("failed test %d - expected retry after %d retries to be %t", i, n, exp)
+			}
+		}
+	}
+}
@@ -2,16 +2,31 @@ package trigger
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang/glog"
 )
 
+// StatusError is the error that is returned when the webhook responds with a
+// non 2xx status code.
+type StatusError struct {
+	Status     string
+	StatusCode int
+}
+
+// Error will return the error message, as required by the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("error webhook; status=%s(%d)", e.Status, e.StatusCode)
+}
+
 // WebhookTrigger is the object that implements http based triggers.
 type WebhookTrigger struct {
 	config Config
@@ -36,27 +51,41 @@ func (s *WebhookTrigger) GetConfig() Config {
 	return s.config
 }
 
-// Execute will trigger the webhook.
-func (s *WebhookTrigger) Execute() error {
+// Execute will trigger the webhook. The given event is available in the
+// templates of the url, headers and body. References to secrets and files in
+// the settings are resolved before each execution.
+func (s *WebhookTrigger) Execute(evt Event) error {
+	settings, err := ResolveSettings(s.config.Settings)
+	if err != nil {
+		return err
+	}
+	wh := &WebhookTrigger{config: s.config}
+	wh.config.Settings = settings
+	return wh.execute(evt)
+}
+
+// execute will trigger the webhook with the settings as they are configured.
+func (s *WebhookTrigger) execute(evt Event) error {
 	cli, err := s.newClient()
 	if err != nil {
 		return err
 	}
-	req, err := s.newRequest()
+	req, err := s.newRequest(evt.Values(s.config.Settings))
 	if err != nil {
 		return err
 	}
+	defer cli.CloseIdleConnections()
 	resp, err := cli.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("error webhook; status=%s(%d)", resp.Status, resp.StatusCode)
+		return &StatusError{Status: resp.Status, StatusCode: resp.StatusCode}
 	}
 	body, _ := ioutil.ReadAll(resp.Body)
 	glog.V(5).Infof("url: %s, status: %s, body: %s", s.config.Settings["url"], resp.Status, body)
-	return nil
+	return s.checkResponse(body)
 }
 
 // newClient will create a new http.Client object with the correct settings, as
@@ -66,40 +95,134 @@ func (s *WebhookTrigger) newClient() (*http.Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	tlscfg, err := getTLSConfig(s.config.Settings)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := s.getProxy()
+	if err != nil {
+		return nil, err
+	}
+	redirect, err := s.getCheckRedirect()
+	if err != nil {
+		return nil, err
+	}
 	return &http.Client{
 		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:           proxy,
+			TLSClientConfig: tlscfg,
+		},
+		CheckRedirect: redirect,
+	}, nil
+}
+
+// getTLSConfig will return the tls configuration as configured with the
+// cafile, certfile, keyfile and insecureskipverify settings.
+func getTLSConfig(settings map[string]string) (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if v := settings["insecureskipverify"]; v != "" {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid insecureSkipVerify '%s'", v)
+		}
+		cfg.InsecureSkipVerify = skip
+	}
+	if ca := settings["cafile"]; ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in caFile '%s'", ca)
+		}
+		cfg.RootCAs = pool
+	}
+	cert, key := settings["certfile"], settings["keyfile"]
+	if cert != "" || key != "" {
+		if cert == "" || key == "" {
+			return nil, fmt.Errorf("both certFile and keyFile should be specified")
+		}
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	return cfg, nil
+}
+
+// getProxy will return the proxy function for the configured proxy. If no
+// proxy is configured, the proxy will be taken from the environment.
+func (s *WebhookTrigger) getProxy() (func(*http.Request) (*url.URL, error), error) {
+	proxy := strings.TrimSpace(s.config.Settings["proxy"])
+	if proxy == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy '%s': %s", proxy, err)
+	}
+	return http.ProxyURL(u), nil
+}
+
+// getCheckRedirect will return the redirect policy for the configured maximum
+// number of redirects. If maxredirects is 0, redirects are not followed, and
+// the redirect response is returned instead. If it is not configured, the
+// default policy of 10 redirects is used.
+func (s *WebhookTrigger) getCheckRedirect() (func(*http.Request, []*http.Request) error, error) {
+	v := strings.TrimSpace(s.config.Settings["maxredirects"])
+	if v == "" {
+		return nil, nil
+	}
+	max, err := strconv.Atoi(v)
+	if err != nil || max < 0 {
+		return nil, fmt.Errorf("invalid maxRedirects '%s'", v)
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > max {
+			return http.ErrUseLastResponse
+		}
+		return nil
 	}, nil
 }
 
 // newRequest will create a http.Request for the configured url, body and
-// method.
-func (s *WebhookTrigger) newRequest() (*http.Request, error) {
+// method. The templates are rendered with the given values. If a signing
+// secret is configured, the rendered body will be signed.
+func (s *WebhookTrigger) newRequest(values map[string]interface{}) (*http.Request, error) {
 	method := s.getMethod()
-	url, err := s.getUrl()
+	url, err := s.getUrl(values)
 	if err != nil {
 		return nil, err
 	}
-	body, err := s.getBody()
+	body, err := s.getBody(values)
 	if err != nil {
 		return nil, err
 	}
+	payload := body.Bytes()
 	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, err
 	}
-	headers, err := s.getHeaders()
+	headers, err := s.getHeaders(values)
 	if err != nil {
 		return nil, err
 	}
 	for headr, val := range headers {
 		req.Header.Set(headr, val)
 	}
+	if err := s.sign(req, payload, time.Now()); err != nil {
+		return nil, err
+	}
 	return req, nil
 }
 
-func (s *WebhookTrigger) getUrl() (string, error) {
+// getUrl will render the configured url with the given values.
+func (s *WebhookTrigger) getUrl(values map[string]interface{}) (string, error) {
 	url := strings.TrimSpace(s.config.Settings["url"])
-	url, err := RenderTemplate(url, s.config.Settings)
+	url, err := RenderTemplate(url, values)
 	if err != nil {
 		return "", err
 	}
@@ -109,11 +232,11 @@ func (s *WebhookTrigger) getUrl() (string, error) {
 	return url, nil
 }
 
-// getBody will process the configured body, and return an io.ReadWriter for
-// that body.
-func (s *WebhookTrigger) getBody() (io.ReadWriter, error) {
+// getBody will process the configured body with the given values, and return
+// a buffer with that body.
+func (s *WebhookTrigger) getBody(values map[string]interface{}) (*bytes.Buffer, error) {
 	buf := new(bytes.Buffer)
-	body, err := RenderTemplate(s.config.Settings["body"], s.config.Settings)
+	body, err := RenderTemplate(s.config.Settings["body"], values)
 	if err != nil {
 		return buf, err
 	}
@@ -148,8 +271,8 @@ func (s *WebhookTrigger) getMethod() string {
 }
 
 // getHeaders will parse the headers configuration, and return a map containing
-// the headers and its' values.
-func (s *WebhookTrigger) getHeaders() (map[string]string, error) {
+// the headers and its' values, rendered with the given values.
+func (s *WebhookTrigger) getHeaders(values map[string]interface{}) (map[string]string, error) {
 	headers := map[string]string{}
 	chdrs := strings.Split(strings.Replace(s.config.Settings["headers"], "\r\n", "\n", -1), "\n")
 	for _, header := range chdrs {
@@ -165,7 +288,7 @@ func (s *WebhookTrigger) getHeaders() (map[string]string, error) {
 		if flds[0] == "" || flds[1] == "" {
 			continue
 		}
-		val, err := RenderTemplate(flds[1], s.config.Settings)
+		val, err := RenderTemplate(flds[1], values)
 		if err != nil {
 			return headers, err
 		}
@@ -1,6 +1,20 @@
 package trigger
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -136,7 +150,7 @@ func TestGetUrl(t *testing.T) {
 	wht := &WebhookTrigger{}
 	for i, tst := range tests {
 		wht.SetConfig(tst.cfg)
-		url, err := wht.getUrl()
+		url, err := wht.getUrl(Event{}.Values(tst.cfg.Settings))
 		if err != nil && !tst.err {
 			t.Errorf("failed test %d - unexpected err when newRequest: %s", i, err)
 		}
@@ -296,7 +310,7 @@ func TestRequest(t *testing.T) {
 	wht := &WebhookTrigger{}
 	for i, tst := range tests {
 		wht.SetConfig(tst.cfg)
-		req, err := wht.newRequest()
+		req, err := wht.newRequest(Event{}.Values(tst.cfg.Settings))
 		if err != nil && !tst.err {
 			t.Errorf("failed test %d - unexpected err when newRequest: %s", i, err)
 		}
@@ -308,3 +322,257 @@ func TestRequest(t *testing.T) {
 		}
 	}
 }
+
+func TestExecute(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		url    string
+		status int
+		err    bool
+	}{
+		{url: srv.URL + "/ok", status: 0, err: false},
+		{url: srv.URL + "/fail", status: http.StatusBadGateway, err: true},
+	}
+	wht := &WebhookTrigger{}
+	for i, tst := range tests {
+		wht.SetConfig(Config{Settings: map[string]string{"url": tst.url}})
+		err := wht.Execute(Event{})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if serr, ok := err.(*StatusError); tst.status != 0 && (!ok || serr.StatusCode != tst.status) {
+			t.Errorf("failed test %d - expected status error %d, got %v", i, tst.status, err)
+		}
+	}
+}
+
+func TestExecuteEvent(t *testing.T) {
+	var body, path string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body, path = string(b), r.URL.Path
+	}))
+	defer srv.Close()
+
+	evt := Event{
+		Time:      time.Date(2019, 3, 4, 18, 0, 0, 0, time.UTC),
+		ScannerId: "development",
+		Schedule:  "Mon-Fri 18:00 replicas=0",
+		Objects: []EventObject{
+			{Namespace: "development", Name: "shell", OldReplicas: 2, NewReplicas: 0},
+			{Namespace: "development", Name: "db", OldReplicas: 1, NewReplicas: 0},
+		},
+	}
+	wht := &WebhookTrigger{}
+	wht.SetConfig(Config{Settings: map[string]string{
+		"url":     srv.URL + "/{{ .ScannerId }}",
+		"channel": "ops",
+		"body":    `{{ .channel }}@{{ .Time.Format "15:04" }}:{{ range .Objects }} {{ .Namespace }}/{{ .Name }} {{ .OldReplicas }}->{{ .NewReplicas }};{{ end }}`,
+	}})
+	if err := wht.Execute(evt); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if path != "/development" {
+		t.Errorf("failed test - expected path /development, got %s", path)
+	}
+	exp := "ops@18:00: development/shell 2->0; development/db 1->0;"
+	if body != exp {
+		t.Errorf("failed test - expected body %q, got %q", exp, body)
+	}
+}
+
+// writeCertificate will create a self-signed certificate and key for
+// localhost, and write them as pem files in the given directory.
+func writeCertificate(t *testing.T, dir, name string) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed test - unable to generate key: %s", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed test - unable to create certificate: %s", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	kder, _ := x509.MarshalECPrivateKey(key)
+	certf := filepath.Join(dir, name+".crt")
+	keyf := filepath.Join(dir, name+".key")
+	ioutil.WriteFile(certf, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyf, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder}), 0600)
+	return certf, keyf, cert
+}
+
+func TestExecuteTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nightshift")
+	if err != nil {
+		t.Fatalf("failed test - unable to create tempdir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	srvcert, srvkey, _ := writeCertificate(t, dir, "server")
+	clicert, clikey, cli := writeCertificate(t, dir, "client")
+	othcert, othkey, _ := writeCertificate(t, dir, "other")
+
+	pair, err := tls.LoadX509KeyPair(srvcert, srvkey)
+	if err != nil {
+		t.Fatalf("failed test - unable to load server certificate: %s", err)
+	}
+	clients := x509.NewCertPool()
+	clients.AddCert(cli)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clients,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		settings map[string]string
+		err      bool
+	}{
+		{
+			settings: map[string]string{},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"cafile": srvcert},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"cafile": srvcert, "certfile": clicert, "keyfile": clikey},
+			err:      false,
+		},
+		{
+			settings: map[string]string{"insecureskipverify": "true", "certfile": clicert, "keyfile": clikey},
+			err:      false,
+		},
+		{
+			settings: map[string]string{"cafile": srvcert, "certfile": othcert, "keyfile": othkey},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"cafile": srvcert, "certfile": clicert},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"cafile": filepath.Join(dir, "missing.crt")},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"insecureskipverify": "maybe"},
+			err:      true,
+		},
+		{
+			settings: map[string]string{"cafile": srvcert, "certfile": clicert, "keyfile": clikey, "responsejsonpath": "status", "responsejsonvalue": "ok"},
+			err:      false,
+		},
+		{
+			settings: map[string]string{"cafile": srvcert, "certfile": clicert, "keyfile": clikey, "responseregex": "failed"},
+			err:      true,
+		},
+	}
+	for i, tst := range tests {
+		tst.settings["url"] = srv.URL
+		tst.settings["timeout"] = "5s"
+		wht := &WebhookTrigger{}
+		wht.SetConfig(Config{Settings: tst.settings})
+		err := wht.Execute(Event{})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+	}
+}
+
+func TestExecuteProxy(t *testing.T) {
+	var host string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.URL.Host
+	}))
+	defer proxy.Close()
+
+	wht := &WebhookTrigger{}
+	wht.SetConfig(Config{Settings: map[string]string{
+		"url":   "http://nightshift.invalid/hook",
+		"proxy": proxy.URL,
+	}})
+	if err := wht.Execute(Event{}); err != nil {
+		t.Errorf("failed test - unexpected err: %s", err)
+	}
+	if host != "nightshift.invalid" {
+		t.Errorf("failed test - expected request through proxy, got host '%s'", host)
+	}
+
+	wht.SetConfig(Config{Settings: map[string]string{
+		"url":   "http://nightshift.invalid/hook",
+		"proxy": "://invalid",
+	}})
+	if err := wht.Execute(Event{}); err == nil {
+		t.Errorf("failed test - expected err for invalid proxy, but got none")
+	}
+}
+
+func TestExecuteRedirect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/twice":
+			http.Redirect(w, r, "/redirect", http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path      string
+		redirects string
+		status    int
+		err       bool
+	}{
+		{path: "/twice", redirects: "", err: false},
+		{path: "/redirect", redirects: "0", status: http.StatusFound, err: true},
+		{path: "/redirect", redirects: "1", err: false},
+		{path: "/twice", redirects: "1", status: http.StatusFound, err: true},
+		{path: "/ok", redirects: "-1", err: true},
+	}
+	wht := &WebhookTrigger{}
+	for i, tst := range tests {
+		wht.SetConfig(Config{Settings: map[string]string{"url": srv.URL + tst.path, "maxredirects": tst.redirects}})
+		err := wht.Execute(Event{})
+		if err != nil && !tst.err {
+			t.Errorf("failed test %d - unexpected err: %s", i, err)
+		}
+		if err == nil && tst.err {
+			t.Errorf("failed test %d - expected err, but got none", i)
+		}
+		if serr, ok := err.(*StatusError); tst.status != 0 && (!ok || serr.StatusCode != tst.status) {
+			t.Errorf("failed test %d - expected status error %d, got %v", i, tst.status, err)
+		}
+	}
+}
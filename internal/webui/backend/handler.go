@@ -38,10 +38,14 @@ func (f *handler) init() {
 	f.mux = httprouter.New()
 	f.mux.GET("/public/*filepath", f.Authenticate(f.ServeFiles("")))
 	f.mux.GET("/api/objects", f.Authenticate(f.GetObjects))
-	f.mux.POST("/api/objects/scale/:replicas", f.Authenticate(f.PostObjectsScale))
-	f.mux.POST("/api/objects/restore", f.Authenticate(f.PostObjectsRestore))
+	f.mux.GET("/api/objects/:uid/explain", f.Authenticate(f.GetObjectExplain))
+	f.mux.POST("/api/objects/*action", f.Authenticate(f.PostObjects))
+	f.mux.GET("/api/activity", f.Authenticate(f.GetActivity))
+	f.mux.GET("/api/clusters", f.Authenticate(f.GetClusters))
 	f.mux.GET("/api/scanners", f.Authenticate(f.GetScanners))
 	f.mux.GET("/api/triggers", f.Authenticate(f.GetTriggers))
+	f.mux.GET("/api/triggers/deadletter", f.Authenticate(f.GetDeadLetters))
+	f.mux.POST("/api/triggers/:id/retry", f.Authenticate(f.PostTriggerRetry))
 	f.mux.GET("/api/version", f.Authenticate(f.GetVersion))
 	f.mux.GET("/metrics", f.Metrics())
 	f.mux.GET("/healthz", f.Healthz)
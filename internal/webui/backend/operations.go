@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 
+	"github.com/joyrex2001/nightshift/internal/activity"
 	"github.com/joyrex2001/nightshift/internal/agent"
 	"github.com/joyrex2001/nightshift/internal/config"
 	"github.com/joyrex2001/nightshift/internal/metrics"
@@ -34,10 +37,15 @@ func (f *handler) GetVersion(w http.ResponseWriter, r *http.Request, ps httprout
 	return
 }
 
-// GetObjects will return the list of currently scanned objects.
+// GetObjects will return the list of currently scanned objects, optionally
+// filtered by the cluster query parameter.
 func (f *handler) GetObjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	cluster, filter := r.URL.Query()["cluster"]
 	res := []*scanner.Object{}
 	for _, obj := range agent.New().GetObjects() {
+		if filter && obj.Cluster != cluster[0] {
+			continue
+		}
 		if len(obj.Schedule) > 0 {
 			res = append(res, obj)
 		}
@@ -49,6 +57,70 @@ func (f *handler) GetObjects(w http.ResponseWriter, r *http.Request, ps httprout
 	return
 }
 
+// GetObjectExplain will return an explanation of how the effective schedule
+// of given object has been determined.
+func (f *handler) GetObjectExplain(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	res, err := agent.New().Explain(ps.ByName("uid"))
+	if err != nil {
+		f.Error(w, r, http.StatusNotFound, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
+// GetActivity will return the activity journal, filtered by the optional
+// namespace, object, trigger and outcome query parameters.
+func (f *handler) GetActivity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	q := r.URL.Query()
+	res := activity.Get(activity.Filter{
+		Namespace: q.Get("namespace"),
+		Object:    q.Get("object"),
+		Trigger:   q.Get("trigger"),
+		Outcome:   q.Get("outcome"),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
+// clusterSummary describes a cluster and the number of scheduled objects
+// that are managed in that cluster.
+type clusterSummary struct {
+	Name    string `json:"name"`
+	Objects int    `json:"objects"`
+}
+
+// GetClusters will return the list of clusters, including the default
+// cluster (with an empty name), with the number of scheduled objects per
+// cluster.
+func (f *handler) GetClusters(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	count := map[string]int{"": 0}
+	for _, cl := range scanner.GetClusters() {
+		count[cl.Name] = 0
+	}
+	for _, obj := range agent.New().GetObjects() {
+		if len(obj.Schedule) > 0 {
+			count[obj.Cluster]++
+		}
+	}
+	res := []clusterSummary{}
+	for name, n := range count {
+		res = append(res, clusterSummary{Name: name, Objects: n})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
 // GetScanners will return the list of active scanners.
 func (f *handler) GetScanners(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	res := []scanner.Config{}
@@ -66,7 +138,7 @@ func (f *handler) GetScanners(w http.ResponseWriter, r *http.Request, ps httprou
 func (f *handler) GetTriggers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	res := []trigger.Config{}
 	for _, trgr := range agent.New().GetTriggers() {
-		res = append(res, trgr.GetConfig())
+		res = append(res, trgr.GetConfig().Redacted())
 	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(res); err != nil {
@@ -75,6 +147,77 @@ func (f *handler) GetTriggers(w http.ResponseWriter, r *http.Request, ps httprou
 	return
 }
 
+// GetDeadLetters will return the list of trigger executions that failed.
+func (f *handler) GetDeadLetters(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	res := agent.New().GetDeadLetters()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
+// PostTriggerRetry will re-run the failed executions of the given trigger.
+func (f *handler) PostTriggerRetry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	n, err := agent.New().RetryTrigger(ps.ByName("id"))
+	if err != nil {
+		f.Error(w, r, http.StatusNotFound, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(map[string]int{"queued": n}); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
+// PostObjects will dispatch the POST requests on objects to the appropriate
+// handler. The routing is done here, as httprouter does not allow the :uid
+// wildcard to be combined with the static scale and restore routes.
+func (f *handler) PostObjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	path := strings.Split(strings.Trim(ps.ByName("action"), "/"), "/")
+	switch {
+	case len(path) == 2 && path[0] == "scale":
+		f.PostObjectsScale(w, r, httprouter.Params{{Key: "replicas", Value: path[1]}})
+	case len(path) == 1 && path[0] == "restore":
+		f.PostObjectsRestore(w, r, ps)
+	case len(path) == 2 && path[1] == "snooze":
+		f.PostObjectSnooze(w, r, httprouter.Params{{Key: "uid", Value: path[0]}})
+	default:
+		f.Error(w, r, http.StatusNotFound, fmt.Errorf("invalid action: %s", ps.ByName("action")))
+	}
+}
+
+// PostObjectSnooze will suppress scheduled events for the given object until
+// the provided time. If no time is provided, the snooze will be removed.
+func (f *handler) PostObjectSnooze(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	obj, ok := agent.New().GetObjects()[ps.ByName("uid")]
+	if !ok {
+		f.Error(w, r, http.StatusNotFound, fmt.Errorf("object not found: %s", ps.ByName("uid")))
+		return
+	}
+	in := struct {
+		Until *time.Time `json:"until"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		f.Error(w, r, http.StatusBadRequest, err)
+		return
+	}
+	start := time.Now()
+	err := obj.Snooze(in.Until)
+	activity.Add(newRecord(obj, "manual_snooze", start, obj.Replicas, obj.Replicas, err))
+	if err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		f.Error(w, r, http.StatusInternalServerError, err)
+	}
+	return
+}
+
 // PostObjectsScale will scale the provided pods to the number of specified
 // replicas.
 func (f *handler) PostObjectsScale(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -92,7 +235,12 @@ func (f *handler) PostObjectsScale(w http.ResponseWriter, r *http.Request, ps ht
 		f.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
-	if err := scaleObjects(in, replicas); err != nil {
+	objs, err := lookupObjects(in)
+	if err != nil {
+		f.Error(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err := scaleObjects(objs, replicas); err != nil {
 		f.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
@@ -109,7 +257,12 @@ func (f *handler) PostObjectsRestore(w http.ResponseWriter, r *http.Request, ps
 		f.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
-	if err := restoreObjects(in); err != nil {
+	objs, err := lookupObjects(in)
+	if err != nil {
+		f.Error(w, r, http.StatusNotFound, err)
+		return
+	}
+	if err := restoreObjects(objs); err != nil {
 		f.Error(w, r, http.StatusInternalServerError, err)
 		return
 	}
@@ -118,15 +271,45 @@ func (f *handler) PostObjectsRestore(w http.ResponseWriter, r *http.Request, ps
 	return
 }
 
+// lookupObjects will return the objects known by the agent for the uids of
+// the given objects. Only the uids of the provided objects are used, so the
+// scanner, cluster and state are always the ones found by nightshift. It will
+// return an error if one or more objects are unknown.
+func lookupObjects(in []*scanner.Object) ([]*scanner.Object, error) {
+	known := agent.New().GetObjects()
+	objs := []*scanner.Object{}
+	missing := []string{}
+	for _, obj := range in {
+		found, ok := known[obj.UID]
+		if !ok {
+			missing = append(missing, obj.UID)
+			continue
+		}
+		objs = append(objs, found)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("objects not found: %s", strings.Join(missing, ","))
+	}
+	return objs, nil
+}
+
 // scaleObjects will scale the array of objects to given amount of replicas.
 func scaleObjects(objects []*scanner.Object, replicas int) error {
 	errs := []string{}
+	woken := []trigger.EventObject{}
 	metrics.Increase("manual_scale")
 	for _, obj := range objects {
-		if _err := obj.Scale(replicas); _err != nil {
+		start := time.Now()
+		old := obj.Replicas
+		_err := obj.Scale(replicas)
+		if _err != nil {
 			errs = append(errs, _err.Error())
+		} else if replicas > 0 {
+			woken = append(woken, newEventObject(obj, old, replicas))
 		}
+		activity.Add(newRecord(obj, "manual_scale", start, old, replicas, _err))
 	}
+	wakeObjects(woken)
 	if len(errs) > 0 {
 		metrics.Increase("manual_scale_error")
 		return fmt.Errorf("%s", strings.Join(errs, ","))
@@ -137,6 +320,7 @@ func scaleObjects(objects []*scanner.Object, replicas int) error {
 // restoreObjects will scale the array of objects to the previous known state.
 func restoreObjects(objects []*scanner.Object) error {
 	errs := []string{}
+	woken := []trigger.EventObject{}
 	metrics.Increase("manual_restore")
 	for _, obj := range objects {
 		if obj.State == nil {
@@ -145,14 +329,59 @@ func restoreObjects(objects []*scanner.Object) error {
 			continue
 		}
 		if obj.State != nil {
-			if _err := obj.Scale(obj.State.Replicas); _err != nil {
+			start := time.Now()
+			old := obj.Replicas
+			_err := obj.Scale(obj.State.Replicas)
+			if _err != nil {
 				errs = append(errs, _err.Error())
+			} else if obj.State.Replicas > 0 {
+				woken = append(woken, newEventObject(obj, old, obj.State.Replicas))
 			}
+			activity.Add(newRecord(obj, "manual_restore", start, old, obj.State.Replicas, _err))
 		}
 	}
+	wakeObjects(woken)
 	if len(errs) > 0 {
 		metrics.Increase("manual_restore_error")
 		return fmt.Errorf("%s", strings.Join(errs, ","))
 	}
 	return nil
 }
+
+// wakeObjects will notify the triggers that the given objects have been
+// woken up manually.
+func wakeObjects(objs []trigger.EventObject) {
+	if len(objs) > 0 {
+		agent.New().Wake(objs)
+	}
+}
+
+// newEventObject will return the trigger event object for given object, that
+// has been scaled manually from old to new replicas.
+func newEventObject(obj *scanner.Object, old, new int) trigger.EventObject {
+	return trigger.EventObject{
+		Namespace:   obj.Namespace,
+		Name:        obj.Name,
+		UID:         obj.UID,
+		ScannerId:   obj.ScannerId,
+		OldReplicas: old,
+		NewReplicas: new,
+	}
+}
+
+// newRecord will return an activity record for a manual action on given
+// object.
+func newRecord(obj *scanner.Object, action string, start time.Time, old, new int, err error) activity.Record {
+	return activity.Record{
+		Time:        start,
+		Action:      action,
+		Namespace:   obj.Namespace,
+		Object:      obj.Name,
+		UID:         obj.UID,
+		ScannerId:   obj.ScannerId,
+		OldReplicas: old,
+		NewReplicas: new,
+		Duration:    time.Since(start),
+		Error:       activity.ErrorString(err),
+	}
+}
@@ -27,6 +27,11 @@ export default new Router({
       name: 'triggers',
       component: () => import('./views/TriggersOverview.vue'),
     },
+    {
+      path: '/activity',
+      name: 'activity',
+      component: () => import('./views/ActivityOverview.vue'),
+    },
     {
       path: '/about',
       name: 'about',